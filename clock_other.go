@@ -0,0 +1,10 @@
+//go:build !wasm
+
+package tangent_sdk
+
+import "time"
+
+// wallNow reads the system clock outside a wasm host, as in tangenttest.
+func wallNow() time.Time {
+	return time.Now()
+}
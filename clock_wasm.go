@@ -0,0 +1,15 @@
+//go:build wasm
+
+package tangent_sdk
+
+import (
+	"time"
+
+	wallclock "github.com/telophasehq/tangent-sdk-go/internal/wasi/clocks/wall-clock"
+)
+
+// wallNow reads the host's wall clock.
+func wallNow() time.Time {
+	now := wallclock.Now()
+	return time.Unix(int64(now.Seconds), int64(now.Nanoseconds))
+}
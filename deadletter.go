@@ -0,0 +1,73 @@
+package tangent_sdk
+
+import (
+	"encoding/binary"
+
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// DeadLetter describes a log whose handler failed.
+type DeadLetter struct {
+	Index int    `json:"index"` // position of the log in its batch
+	Error string `json:"error"` // handler error message
+	Log   string `json:"log"`   // original log text
+}
+
+// MarshalEasyJSON writes d as {"index":..,"error":..,"log":..}.
+func (d DeadLetter) MarshalEasyJSON(w *jwriter.Writer) {
+	w.RawString(`{"index":`)
+	w.Int(d.Index)
+	w.RawString(`,"error":`)
+	w.String(d.Error)
+	w.RawString(`,"log":`)
+	w.String(d.Log)
+	w.RawByte('}')
+}
+
+// MarshalMsg appends d to b as a MessagePack map with the same keys as its
+// JSON form.
+func (d DeadLetter) MarshalMsg(b []byte) ([]byte, error) {
+	b = append(b, 0x83)
+	b = appendMsgpString(b, "index")
+	b = append(b, 0xd3)
+	b = binary.BigEndian.AppendUint64(b, uint64(d.Index))
+	b = appendMsgpString(b, "error")
+	b = appendMsgpString(b, d.Error)
+	b = appendMsgpString(b, "log")
+	b = appendMsgpString(b, d.Log)
+	return b, nil
+}
+
+// inlineDeadLetter is the output record written for WithInlineDeadLetters:
+// {"dead_letter":{...}}. It supports the JSON and MessagePack encoders.
+type inlineDeadLetter struct {
+	DeadLetter DeadLetter `json:"dead_letter"`
+}
+
+func (d inlineDeadLetter) MarshalEasyJSON(w *jwriter.Writer) {
+	w.RawString(`{"dead_letter":`)
+	d.DeadLetter.MarshalEasyJSON(w)
+	w.RawByte('}')
+}
+
+func (d inlineDeadLetter) MarshalMsg(b []byte) ([]byte, error) {
+	b = append(b, 0x81)
+	b = appendMsgpString(b, "dead_letter")
+	return d.DeadLetter.MarshalMsg(b)
+}
+
+func appendMsgpString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= 0xff:
+		b = append(b, 0xd9, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0xda)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xdb)
+		b = binary.BigEndian.AppendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
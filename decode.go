@@ -0,0 +1,389 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMissing reports that a required path was absent from the log.
+	ErrMissing = errors.New("missing")
+	// ErrWrongKind reports that a path held a scalar of an unexpected kind.
+	ErrWrongKind = errors.New("wrong kind")
+	// ErrUnsupported reports a struct field type Decode cannot fill.
+	ErrUnsupported = errors.New("unsupported field type")
+)
+
+// FieldError describes a single field that could not be decoded.
+type FieldError struct {
+	Path  string // log path, e.g. "detail.findings[0].CompanyName"
+	Field string // Go field name, e.g. "Finding.Company"
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Path, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError lists every field that failed to decode.
+type DecodeError struct {
+	Errors []*FieldError
+}
+
+func (e *DecodeError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("decode: %d field error(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *DecodeError) Unwrap() []error {
+	out := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		out[i] = fe
+	}
+	return out
+}
+
+// FieldErrors collects field failures while decoding. Generated
+// DecodeFromLog methods and Decode both report through it.
+type FieldErrors []*FieldError
+
+// Add records err for the field at path.
+func (e *FieldErrors) Add(path, field string, err error) {
+	*e = append(*e, &FieldError{Path: path, Field: field, Err: err})
+}
+
+// Missing records that a required path was absent.
+func (e *FieldErrors) Missing(path, field string) {
+	e.Add(path, field, ErrMissing)
+}
+
+// WrongKind records that path held a scalar of kind got instead of want.
+func (e *FieldErrors) WrongKind(path, field, want string, got Kind) {
+	e.Add(path, field, wrongKind(want, got))
+}
+
+// NotTime records that path held a value ParseTime could not read.
+func (e *FieldErrors) NotTime(path, field string, v Value) {
+	e.Add(path, field, notTime(v))
+}
+
+// Overflow records that the integer at path does not fit the field's type.
+func (e *FieldErrors) Overflow(path, field string, v int64, typ string) {
+	e.Add(path, field, fmt.Errorf("%w: %d overflows %s", ErrWrongKind, v, typ))
+}
+
+// Err returns a *DecodeError if any failures were recorded, else nil.
+func (e FieldErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return &DecodeError{Errors: e}
+}
+
+// LogDecoder is implemented by types with generated decoders. Decode uses
+// DecodeFromLog instead of reflection when dst implements it.
+type LogDecoder interface {
+	DecodeFromLog(Log) error
+}
+
+func wrongKind(want string, got Kind) error {
+	return fmt.Errorf("%w: want %s, got %s", ErrWrongKind, want, got)
+}
+
+func notTime(v Value) error {
+	if s, ok := v.AsString(); ok {
+		return fmt.Errorf("%w: %q is not a timestamp", ErrWrongKind, s)
+	}
+	return wrongKind("timestamp", v.Kind())
+}
+
+var (
+	valueType = reflect.TypeOf(Value{})
+	timeType  = reflect.TypeOf(time.Time{})
+)
+
+// Decode fills the struct pointed to by dst from l using `tangent:"path"`
+// struct tags:
+//
+//	type Finding struct {
+//		Company  string            `tangent:"detail.findings[0].CompanyName"`
+//		Severity *float64          `tangent:"detail.severity"`
+//		Tags     []string          `tangent:"detail.tags"`
+//		Labels   map[string]string `tangent:"labels,optional"`
+//		Resource struct {
+//			ID string `tangent:"id"`
+//		} `tangent:"detail.resource"`
+//	}
+//
+// Paths of nested structs are relative to the parent field's path. Pointer
+// fields and fields tagged ",optional" are left zero when their path is
+// missing; every other tagged field is required. Slices are read with
+// GetList (or element by element for slices of structs) and maps with
+// GetMap. time.Time fields are read with ParseTime, so any format it
+// detects is accepted. Fields without a tag are skipped, except embedded
+// structs, whose fields are decoded at the parent's path.
+//
+// Decode keeps going after a field fails and returns a *DecodeError listing
+// every path that was missing, held the wrong kind of scalar, or held
+// something other than a timestamp for a time.Time field.
+func Decode(l Log, dst any) error {
+	if ld, ok := dst.(LogDecoder); ok {
+		return ld.DecodeFromLog(l)
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("tangent: Decode requires a non-nil pointer to a struct")
+	}
+	d := decoder{log: l}
+	d.decodeStruct("", "", rv.Elem())
+	return d.errs.Err()
+}
+
+type decoder struct {
+	log  Log
+	errs FieldErrors
+}
+
+func (d *decoder) fail(path, field string, err error) {
+	d.errs.Add(path, field, err)
+}
+
+func (d *decoder) decodeStruct(prefix, owner string, sv reflect.Value) {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		tag, ok := sf.Tag.Lookup("tangent")
+		if tag == "-" {
+			continue
+		}
+		field := sf.Name
+		if owner != "" {
+			field = owner + "." + sf.Name
+		}
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				d.decodeStruct(prefix, owner, sv.Field(i))
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		optional := opts == "optional"
+		d.decodeField(joinPath(prefix, name), field, sv.Field(i), optional)
+	}
+}
+
+func (d *decoder) decodeField(path, field string, fv reflect.Value, optional bool) {
+	if fv.Kind() == reflect.Pointer {
+		elem := reflect.New(fv.Type().Elem())
+		if d.decodeValue(path, field, elem.Elem(), true) {
+			fv.Set(elem)
+		}
+		return
+	}
+	d.decodeValue(path, field, fv, optional)
+}
+
+// decodeValue fills v from path and reports whether the path was present.
+func (d *decoder) decodeValue(path, field string, v reflect.Value, optional bool) bool {
+	t := v.Type()
+	switch {
+	case t == valueType:
+		val, ok := d.log.Get(path)
+		if !ok {
+			d.missing(path, field, optional)
+			return false
+		}
+		v.Set(reflect.ValueOf(val))
+		return true
+
+	case t.Kind() == reflect.Struct && t != timeType:
+		if !d.log.Has(path) {
+			d.missing(path, field, optional)
+			return false
+		}
+		d.decodeStruct(path, field, v)
+		return true
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		val, ok := d.log.Get(path)
+		if !ok {
+			d.missing(path, field, optional)
+			return false
+		}
+		if b, ok := val.AsBytes(); ok {
+			v.SetBytes(b)
+		} else if s, ok := val.AsString(); ok {
+			v.SetBytes([]byte(s))
+		} else {
+			d.errs.WrongKind(path, field, "bytes", val.Kind())
+		}
+		return true
+
+	case t.Kind() == reflect.Slice && isStructLike(t.Elem()):
+		n := d.log.Len(path)
+		if n == nil {
+			d.missing(path, field, optional)
+			return false
+		}
+		out := reflect.MakeSlice(t, int(*n), int(*n))
+		for i := 0; i < int(*n); i++ {
+			d.decodeField(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("%s[%d]", field, i), out.Index(i), false)
+		}
+		v.Set(out)
+		return true
+
+	case t.Kind() == reflect.Slice:
+		vals, ok := d.log.GetList(path)
+		if !ok {
+			d.missing(path, field, optional)
+			return false
+		}
+		out := reflect.MakeSlice(t, len(vals), len(vals))
+		for i, val := range vals {
+			if err := setScalar(out.Index(i), val); err != nil {
+				d.fail(fmt.Sprintf("%s[%d]", path, i), field, err)
+			}
+		}
+		v.Set(out)
+		return true
+
+	case t.Kind() == reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			d.fail(path, field, ErrUnsupported)
+			return false
+		}
+		fields, ok := d.log.GetMap(path)
+		if !ok {
+			d.missing(path, field, optional)
+			return false
+		}
+		out := reflect.MakeMapWithSize(t, len(fields))
+		for _, f := range fields {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := setScalar(elem, f.Value); err != nil {
+				d.fail(joinPath(path, f.Key), field, err)
+				continue
+			}
+			out.SetMapIndex(reflect.ValueOf(f.Key).Convert(t.Key()), elem)
+		}
+		v.Set(out)
+		return true
+
+	default:
+		val, ok := d.log.Get(path)
+		if !ok {
+			d.missing(path, field, optional)
+			return false
+		}
+		if err := setScalar(v, val); err != nil {
+			d.fail(path, field, err)
+		}
+		return true
+	}
+}
+
+func (d *decoder) missing(path, field string, optional bool) {
+	if !optional {
+		d.errs.Missing(path, field)
+	}
+}
+
+func isStructLike(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != valueType && t != timeType
+}
+
+// setScalar stores val into v, converting between compatible Go types.
+func setScalar(v reflect.Value, val Value) error {
+	switch v.Type() {
+	case valueType:
+		v.Set(reflect.ValueOf(val))
+		return nil
+	case timeType:
+		ts, f := ParseTime(val)
+		if f == TimeUnknown {
+			return notTime(val)
+		}
+		v.Set(reflect.ValueOf(ts))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := val.AsString()
+		if !ok {
+			return wrongKind("string", val.Kind())
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := val.AsBool()
+		if !ok {
+			return wrongKind("bool", val.Kind())
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := val.AsInt()
+		if !ok {
+			return wrongKind("int", val.Kind())
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("%w: %d overflows %s", ErrWrongKind, i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := val.AsInt()
+		if !ok {
+			return wrongKind("int", val.Kind())
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("%w: %d overflows %s", ErrWrongKind, i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		if f, ok := val.AsFloat(); ok {
+			v.SetFloat(f)
+		} else if i, ok := val.AsInt(); ok {
+			v.SetFloat(float64(i))
+		} else {
+			return wrongKind("float", val.Kind())
+		}
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return ErrUnsupported
+		}
+		if x := val.Interface(); x != nil {
+			v.Set(reflect.ValueOf(x))
+		}
+	default:
+		return ErrUnsupported
+	}
+	return nil
+}
+
+// joinPath appends a relative path to prefix.
+func joinPath(prefix, rel string) string {
+	switch {
+	case prefix == "":
+		return rel
+	case rel == "":
+		return prefix
+	case rel[0] == '[':
+		return prefix + rel
+	default:
+		return prefix + "." + rel
+	}
+}
@@ -0,0 +1,191 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type decodeResource struct {
+	ID   string `tangent:"id"`
+	Zone string `tangent:"placement.zone,optional"`
+}
+
+type decodeBase struct {
+	Source string `tangent:"source"`
+}
+
+type decodeFinding struct {
+	decodeBase
+	Company   string            `tangent:"detail.findings[0].CompanyName"`
+	Severity  *float64          `tangent:"detail.severity"`
+	Count     *int              `tangent:"detail.count"`
+	Note      string            `tangent:"detail.note,optional"`
+	Tags      []string          `tangent:"detail.tags"`
+	Ports     []uint16          `tangent:"detail.ports,optional"`
+	Labels    map[string]string `tangent:"labels,optional"`
+	Resource  decodeResource    `tangent:"detail.resource"`
+	Resources []decodeResource  `tangent:"detail.resources,optional"`
+	Owner     *decodeResource   `tangent:"detail.owner"`
+	Raw       Value             `tangent:"detail.findings[0].CompanyName"`
+	Ignored   string
+	Skipped   string `tangent:"-"`
+}
+
+func TestDecode(t *testing.T) {
+	l := testLogs(t, `{
+		"source": "scanner",
+		"detail": {
+			"findings": [{"CompanyName": "Acme"}],
+			"severity": 7,
+			"tags": ["a", "b"],
+			"ports": [22, 443],
+			"resource": {"id": "r-1", "placement": {"zone": "us-east-1a"}},
+			"resources": [{"id": "r-2"}, {"id": "r-3", "placement": {"zone": "z"}}]
+		},
+		"labels": {"env": "prod", "team": "core"},
+		"Ignored": "x",
+		"Skipped": "x"
+	}`)[0]
+	var got decodeFinding
+	if err := Decode(l, &got); err != nil {
+		t.Fatal(err)
+	}
+	sev := 7.0
+	raw, _ := l.Get("detail.findings[0].CompanyName")
+	want := decodeFinding{
+		decodeBase: decodeBase{Source: "scanner"},
+		Company:    "Acme",
+		Severity:   &sev,
+		Tags:       []string{"a", "b"},
+		Ports:      []uint16{22, 443},
+		Labels:     map[string]string{"env": "prod", "team": "core"},
+		Resource:   decodeResource{ID: "r-1", Zone: "us-east-1a"},
+		Resources:  []decodeResource{{ID: "r-2"}, {ID: "r-3", Zone: "z"}},
+		Raw:        raw,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Optional and pointer fields stay zero when missing; an empty list is
+	// present.
+	got = decodeFinding{}
+	l = testLogs(t, `{"source":"s","detail":{"findings":[{"CompanyName":"c"}],"tags":[],"resource":{"id":"r"}}}`)[0]
+	if err := Decode(l, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Severity != nil || got.Count != nil || got.Owner != nil || got.Labels != nil || got.Ports != nil || got.Resources != nil {
+		t.Errorf("missing optional fields decoded as %+v", got)
+	}
+	if got.Tags == nil || len(got.Tags) != 0 {
+		t.Errorf("Tags = %#v, want an empty slice", got.Tags)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	l := testLogs(t, `{
+		"detail": {
+			"findings": [{"CompanyName": 1}],
+			"severity": "high",
+			"count": 1.5,
+			"tags": ["a", 2, "c", false],
+			"ports": [80, 70000, -1],
+			"resource": {"placement": {}},
+			"resources": [{"id": "ok"}, {}],
+			"owner": {"id": true}
+		},
+		"labels": {"env": "prod", "n": 3}
+	}`)[0]
+	var got decodeFinding
+	err := Decode(l, &got)
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("Decode = %v, want a *DecodeError", err)
+	}
+	type failure struct {
+		path, field string
+		err         error
+	}
+	want := []failure{
+		{"source", "Source", ErrMissing},
+		{"detail.findings[0].CompanyName", "Company", ErrWrongKind},
+		{"detail.severity", "Severity", ErrWrongKind},
+		{"detail.count", "Count", ErrWrongKind},
+		{"detail.tags[1]", "Tags", ErrWrongKind},
+		{"detail.tags[3]", "Tags", ErrWrongKind},
+		{"detail.ports[1]", "Ports", ErrWrongKind},
+		{"detail.ports[2]", "Ports", ErrWrongKind},
+		{"labels.n", "Labels", ErrWrongKind},
+		{"detail.resource.id", "Resource.ID", ErrMissing},
+		{"detail.resources[1].id", "Resources[1].ID", ErrMissing},
+		{"detail.owner.id", "Owner.ID", ErrWrongKind},
+	}
+	var failures []failure
+	for _, fe := range de.Errors {
+		failures = append(failures, failure{fe.Path, fe.Field, fe.Err})
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("Decode failed on %d fields, want %d:\n%v", len(failures), len(want), err)
+	}
+	for i, f := range failures {
+		if f.path != want[i].path || f.field != want[i].field || !errors.Is(f.err, want[i].err) {
+			t.Errorf("failure %d = %s (%s): %v; want %s (%s): %v", i, f.path, f.field, f.err, want[i].path, want[i].field, want[i].err)
+		}
+	}
+	if !errors.Is(err, ErrMissing) || !errors.Is(err, ErrWrongKind) {
+		t.Errorf("%v does not unwrap to both ErrMissing and ErrWrongKind", err)
+	}
+
+	// Decoding carries on past failures.
+	if got.Tags[0] != "a" || got.Tags[2] != "c" || got.Labels["env"] != "prod" || got.Resources[0].ID != "ok" {
+		t.Errorf("fields around the failures = %+v", got)
+	}
+
+	if err := Decode(l, got); err == nil {
+		t.Error("Decode into a struct value succeeded")
+	}
+	var unsupported struct {
+		M map[int]string `tangent:"labels"`
+		C chan int       `tangent:"source,optional"`
+	}
+	err = Decode(testLogs(t, `{"labels":{},"source":"s"}`)[0], &unsupported)
+	if !errors.As(err, &de) || len(de.Errors) != 2 || !errors.Is(de.Errors[0], ErrUnsupported) || !errors.Is(de.Errors[1], ErrUnsupported) {
+		t.Errorf("Decode of unsupported fields = %v", err)
+	}
+}
+
+func TestDecodeTime(t *testing.T) {
+	type event struct {
+		At    time.Time   `tangent:"at"`
+		Seen  *time.Time  `tangent:"seen"`
+		Times []time.Time `tangent:"times,optional"`
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var ev event
+	l := testLogs(t, `{"at":"2024-01-02T03:04:05Z","seen":1704164645000,"times":[1704164645]}`)[0]
+	if err := Decode(l, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if !ev.At.Equal(want) || ev.Seen == nil || !ev.Seen.Equal(want) || len(ev.Times) != 1 || !ev.Times[0].Equal(want) {
+		t.Errorf("Decode = %+v", ev)
+	}
+
+	ev = event{}
+	l = testLogs(t, `{"at":"yesterday","times":[true]}`)[0]
+	err := Decode(l, &ev)
+	var de *DecodeError
+	if !errors.As(err, &de) || len(de.Errors) != 2 {
+		t.Fatalf("Decode = %v, want 2 field errors", err)
+	}
+	for _, fe := range de.Errors {
+		if !errors.Is(fe, ErrWrongKind) {
+			t.Errorf("%v is not ErrWrongKind", fe)
+		}
+	}
+	if got := de.Errors[0].Error(); got != `at (At): wrong kind: "yesterday" is not a timestamp` {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package tangent_sdk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// Encoder writes output records to the buffer returned from process-logs.
+// Select one with WithEncoder; the default is NDJSON.
+type Encoder interface {
+	// Encode appends one record for v to buf.
+	Encode(buf *bytes.Buffer, v any) error
+}
+
+// MsgpMarshaler is implemented by types with MessagePack marshallers, such
+// as those generated by github.com/tinylib/msgp.
+type MsgpMarshaler interface {
+	MarshalMsg(b []byte) ([]byte, error)
+}
+
+// ProtoMarshaler is implemented by protobuf messages with generated
+// marshallers, such as gogoproto types.
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// VTProtoMarshaler is implemented by messages generated with vtprotobuf.
+type VTProtoMarshaler interface {
+	MarshalVT() ([]byte, error)
+}
+
+var (
+	// NDJSON writes each record as a line of JSON using its easyjson
+	// marshaller. Outputs must implement easyjson.Marshaler; run gen to
+	// generate one.
+	NDJSON Encoder = ndjsonEncoder{}
+
+	// JSON writes each record as a line of JSON using encoding/json. It needs
+	// no generated code but is slower than NDJSON, particularly under TinyGo.
+	JSON Encoder = jsonEncoder{}
+
+	// MessagePack writes records back to back as MessagePack. Outputs must
+	// implement MsgpMarshaler.
+	MessagePack Encoder = msgpackEncoder{}
+
+	// ProtobufDelimited writes each record as a protobuf message prefixed with
+	// its varint-encoded length, the framing used by Java's writeDelimitedTo
+	// and Go's protodelim. Outputs must implement VTProtoMarshaler or
+	// ProtoMarshaler.
+	ProtobufDelimited Encoder = protobufDelimitedEncoder{}
+)
+
+// WithEncoder selects how Wire writes output records.
+func WithEncoder(enc Encoder) Option {
+	return func(o *options) { o.encoder = enc }
+}
+
+// easyjsonFuncs holds marshallers registered with RegisterEasyJSON.
+var easyjsonFuncs = map[reflect.Type]func(*jwriter.Writer, any){}
+
+// RegisterEasyJSON makes NDJSON marshal outputs of type T, or *T, with fn.
+// gen registers marshallers this way for output types declared in other
+// packages, which it can't add methods to. Call it only from init.
+func RegisterEasyJSON[T any](fn func(*jwriter.Writer, T)) {
+	easyjsonFuncs[reflect.TypeOf((*T)(nil)).Elem()] = func(w *jwriter.Writer, v any) {
+		fn(w, v.(T))
+	}
+}
+
+// marshalRegistered writes v with its registered marshaller, if it has one.
+func marshalRegistered(w *jwriter.Writer, v any) bool {
+	if fn, ok := easyjsonFuncs[reflect.TypeOf(v)]; ok {
+		fn(w, v)
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		return false
+	}
+	fn, ok := easyjsonFuncs[rv.Type().Elem()]
+	if !ok {
+		return false
+	}
+	if rv.IsNil() {
+		w.RawString("null")
+	} else {
+		fn(w, rv.Elem().Interface())
+	}
+	return true
+}
+
+type ndjsonEncoder struct{}
+
+func (ndjsonEncoder) Encode(buf *bytes.Buffer, v any) error {
+	var jw jwriter.Writer
+	if m, ok := v.(easyjson.Marshaler); ok {
+		m.MarshalEasyJSON(&jw)
+	} else if !marshalRegistered(&jw, v) {
+		return fmt.Errorf("output %T does not implement easyjson.Marshaler; run gen and recompile", v)
+	}
+	jw.RawByte('\n')
+	if jw.Error != nil {
+		return jw.Error
+	}
+	_, err := jw.DumpTo(buf)
+	return err
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(buf *bytes.Buffer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	buf.WriteByte('\n')
+	return nil
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Encode(buf *bytes.Buffer, v any) error {
+	m, ok := v.(MsgpMarshaler)
+	if !ok {
+		return fmt.Errorf("output %T does not implement MarshalMsg", v)
+	}
+	b, err := m.MarshalMsg(buf.AvailableBuffer())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+type protobufDelimitedEncoder struct{}
+
+func (protobufDelimitedEncoder) Encode(buf *bytes.Buffer, v any) error {
+	var b []byte
+	var err error
+	switch m := v.(type) {
+	case VTProtoMarshaler:
+		b, err = m.MarshalVT()
+	case ProtoMarshaler:
+		b, err = m.Marshal()
+	default:
+		return fmt.Errorf("output %T does not implement Marshal or MarshalVT", v)
+	}
+	if err != nil {
+		return err
+	}
+	buf.Write(binary.AppendUvarint(buf.AvailableBuffer(), uint64(len(b))))
+	buf.Write(b)
+	return nil
+}
@@ -0,0 +1,156 @@
+package tangent_sdk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// msgRecord marshals itself as a MessagePack map {"id": uint, "name": str}
+// using only the fixed-size formats it needs.
+type msgRecord struct {
+	ID   uint8
+	Name string
+}
+
+func (r msgRecord) MarshalMsg(b []byte) ([]byte, error) {
+	if r.ID > 0x7f || len(r.Name) > 31 {
+		return b, errors.New("msgRecord: out of range")
+	}
+	b = append(b, 0x82) // fixmap, 2 entries
+	b = append(b, 0xa2, 'i', 'd', r.ID)
+	b = append(b, 0xa4, 'n', 'a', 'm', 'e', 0xa0|byte(len(r.Name)))
+	return append(b, r.Name...), nil
+}
+
+// readMsgRecord reads one record written by msgRecord.MarshalMsg.
+func readMsgRecord(r *bytes.Reader) (msgRecord, error) {
+	var out msgRecord
+	str := func() (string, error) {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c&0xe0 != 0xa0 {
+			return "", fmt.Errorf("want fixstr, got %#x", c)
+		}
+		s := make([]byte, c&0x1f)
+		_, err = io.ReadFull(r, s)
+		return string(s), err
+	}
+	if c, err := r.ReadByte(); err != nil || c != 0x82 {
+		return out, fmt.Errorf("want fixmap of 2, got %#x, %v", c, err)
+	}
+	for i := 0; i < 2; i++ {
+		key, err := str()
+		if err != nil {
+			return out, err
+		}
+		switch key {
+		case "id":
+			if out.ID, err = r.ReadByte(); err != nil {
+				return out, err
+			}
+		case "name":
+			if out.Name, err = str(); err != nil {
+				return out, err
+			}
+		default:
+			return out, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return out, nil
+}
+
+func TestMessagePackRoundTrip(t *testing.T) {
+	records := []msgRecord{{1, "a"}, {0, ""}, {127, strings.Repeat("x", 31)}}
+	var buf bytes.Buffer
+	for _, rec := range records {
+		if err := MessagePack.Encode(&buf, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := bytes.NewReader(buf.Bytes())
+	for i, want := range records {
+		got, err := readMsgRecord(r)
+		if err != nil || got != want {
+			t.Fatalf("record %d = %+v, %v; want %+v", i, got, err, want)
+		}
+	}
+	if r.Len() != 0 {
+		t.Errorf("%d trailing bytes", r.Len())
+	}
+
+	n := buf.Len()
+	if err := MessagePack.Encode(&buf, msgRecord{ID: 200}); err == nil {
+		t.Error("MessagePack encoded a record whose marshaller failed")
+	}
+	if err := MessagePack.Encode(&buf, testOut{}); err == nil {
+		t.Error("MessagePack encoded a record without MarshalMsg")
+	}
+	if buf.Len() != n {
+		t.Errorf("failed encodes wrote %d bytes", buf.Len()-n)
+	}
+}
+
+// protoRecord marshals itself as its payload, failing for "fail".
+type protoRecord string
+
+func (r protoRecord) Marshal() ([]byte, error) {
+	if r == "fail" {
+		return nil, errors.New("protoRecord: fail")
+	}
+	return []byte(r), nil
+}
+
+// vtRecord implements both marshallers; ProtobufDelimited must pick
+// MarshalVT.
+type vtRecord string
+
+func (r vtRecord) Marshal() ([]byte, error)   { return []byte("gogo"), nil }
+func (r vtRecord) MarshalVT() ([]byte, error) { return []byte("vt:" + r), nil }
+
+func TestProtobufDelimitedRoundTrip(t *testing.T) {
+	records := []any{protoRecord("a"), protoRecord(""), protoRecord(strings.Repeat("y", 300)), vtRecord("b")}
+	want := []string{"a", "", strings.Repeat("y", 300), "vt:b"}
+	var buf bytes.Buffer
+	for _, rec := range records {
+		if err := ProtobufDelimited.Encode(&buf, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each record is its length then its payload; 300 needs two bytes.
+	if size := (1 + 1) + (1 + 0) + (2 + 300) + (1 + 4); buf.Len() != size {
+		t.Errorf("encoded %d bytes, want %d", buf.Len(), size)
+	}
+	r := bytes.NewReader(buf.Bytes())
+	for i, w := range want {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatalf("record %d length: %v", i, err)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil || string(msg) != w {
+			t.Fatalf("record %d = %q, %v; want %q", i, msg, err, w)
+		}
+	}
+	if r.Len() != 0 {
+		t.Errorf("%d trailing bytes", r.Len())
+	}
+
+	n := buf.Len()
+	if err := ProtobufDelimited.Encode(&buf, protoRecord("fail")); err == nil {
+		t.Error("ProtobufDelimited encoded a record whose marshaller failed")
+	}
+	if err := ProtobufDelimited.Encode(&buf, testOut{}); err == nil {
+		t.Error("ProtobufDelimited encoded a record without Marshal")
+	}
+	if buf.Len() != n {
+		t.Errorf("failed encodes wrote %d bytes", buf.Len()-n)
+	}
+}
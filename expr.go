@@ -0,0 +1,294 @@
+package tangent_sdk
+
+// Expr is a boolean expression over predicates. Predicate and Selector are
+// expressions, and And, Or and Not combine them:
+//
+//	tangent_sdk.And(
+//		tangent_sdk.EqString("source", "aws"),
+//		tangent_sdk.Or(tangent_sdk.EqString("type", "A"), tangent_sdk.EqString("type", "B")),
+//		tangent_sdk.Not(tangent_sdk.Has("test")),
+//	)
+//
+// Selectors flattens an expression into the Any/All/None form the host
+// understands.
+type Expr interface {
+	// dnf returns the expression in disjunctive normal form.
+	dnf() []conj
+	matches(Log) bool
+}
+
+// conj is a conjunction of terms. An empty conj is true; an empty []conj is
+// false.
+type conj []term
+
+type andExpr []Expr
+type orExpr []Expr
+type notExpr struct{ e Expr }
+
+// And matches when every expression matches. And() is true.
+func And(exprs ...Expr) Expr { return andExpr(exprs) }
+
+// Or matches when at least one expression matches. Or() is false.
+func Or(exprs ...Expr) Expr { return orExpr(exprs) }
+
+// Not matches when e does not.
+func Not(e Expr) Expr { return notExpr{e} }
+
+func (a andExpr) dnf() []conj {
+	out := []conj{{}}
+	for _, e := range a {
+		out = simplify(product(out, e.dnf()))
+	}
+	return out
+}
+
+func (a andExpr) matches(l Log) bool {
+	for _, e := range a {
+		if !e.matches(l) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o orExpr) dnf() []conj {
+	var out []conj
+	for _, e := range o {
+		out = append(out, e.dnf()...)
+	}
+	return out
+}
+
+func (o orExpr) matches(l Log) bool {
+	for _, e := range o {
+		if e.matches(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// dnf applies De Morgan: NOT (c1 OR c2) == (NOT c1) AND (NOT c2), where each
+// NOT c is an OR of negated terms.
+func (n notExpr) dnf() []conj {
+	out := []conj{{}}
+	for _, c := range n.e.dnf() {
+		alt := make([]conj, len(c))
+		for i, t := range c {
+			alt[i] = conj{not(t)}
+		}
+		out = simplify(product(out, alt))
+	}
+	return out
+}
+
+func (n notExpr) matches(l Log) bool {
+	return !n.e.matches(l)
+}
+
+func (p Predicate) dnf() []conj {
+	return []conj{append(conj(nil), p.terms...)}
+}
+
+func (s Selector) dnf() []conj {
+	var out []conj
+	for _, c := range s.clauses() {
+		base := make(conj, 0, len(c.all)+len(c.none))
+		base = append(base, c.all...)
+		for _, t := range c.none {
+			base = append(base, not(t))
+		}
+		if len(c.any) == 0 {
+			out = append(out, base)
+			continue
+		}
+		for _, t := range c.any {
+			out = append(out, append(append(conj(nil), base...), t))
+		}
+	}
+	return out
+}
+
+// product ANDs two expressions in DNF.
+func product(a, b []conj) []conj {
+	out := make([]conj, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			out = append(out, append(append(conj(nil), x...), y...))
+		}
+	}
+	return out
+}
+
+// Selectors flattens e into the smallest list of selectors it can find whose
+// union matches the same logs, for passing to Wire:
+//
+//	tangent_sdk.Wire(meta, tangent_sdk.Selectors(expr), handler, nil)
+//
+// Duplicate terms, contradictory conjunctions and conjunctions subsumed by
+// others are dropped, and conjunctions that differ in a single term are
+// merged into one selector's Any list.
+func Selectors(e Expr) []Selector {
+	var groups []group
+	for _, c := range simplify(e.dnf()) {
+		merged := false
+		for i := range groups {
+			if groups[i].merge(c) {
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			groups = append(groups, group{base: c})
+		}
+	}
+
+	out := make([]Selector, len(groups))
+	for i, g := range groups {
+		for _, t := range g.base {
+			if t.negate {
+				out[i].None = append(out[i].None, pred(not(t)))
+			} else {
+				out[i].All = append(out[i].All, pred(t))
+			}
+		}
+		for _, t := range g.any {
+			out[i].Any = append(out[i].Any, pred(t))
+		}
+	}
+	return out
+}
+
+// simplify removes duplicate terms, contradictions and subsumed
+// conjunctions.
+func simplify(d []conj) []conj {
+	var cs []conj
+	for _, c := range d {
+		if c, ok := c.normalize(); ok {
+			cs = append(cs, c)
+		}
+	}
+	var out []conj
+	for i, c := range cs {
+		subsumed := false
+		for j, o := range cs {
+			if i != j && o.subsetOf(c) && (len(o) < len(c) || j < i) {
+				subsumed = true
+				break
+			}
+		}
+		if !subsumed {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// normalize drops duplicate terms and reports false if c contains both a
+// term and its negation.
+func (c conj) normalize() (conj, bool) {
+	out := make(conj, 0, len(c))
+	for _, t := range c {
+		if out.has(t) {
+			continue
+		}
+		if out.has(not(t)) {
+			return nil, false
+		}
+		out = append(out, t)
+	}
+	return out, true
+}
+
+func (c conj) subsetOf(o conj) bool {
+	for _, t := range c {
+		if !o.has(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c conj) has(t term) bool {
+	for _, x := range c {
+		if x.equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// group is a selector being assembled: every base term plus any one of the
+// any terms.
+type group struct {
+	base conj
+	any  []term
+}
+
+// merge adds c to g if c is g's base plus one more un-negated term, turning
+// a plain conjunction into base-plus-Any form when needed.
+func (g *group) merge(c conj) bool {
+	if len(g.any) == 0 {
+		// g is a single conjunction; merge when c and g.base share all but
+		// one un-negated term each.
+		if len(c) != len(g.base) {
+			return false
+		}
+		p, rest, ok := splitExtra(g.base, c)
+		if !ok {
+			return false
+		}
+		q, _, ok := splitExtra(c, g.base)
+		if !ok {
+			return false
+		}
+		g.base, g.any = rest, []term{p, q}
+		return true
+	}
+	if len(c) != len(g.base)+1 || !g.base.subsetOf(c) {
+		return false
+	}
+	for _, t := range c {
+		if !g.base.has(t) {
+			if t.negate {
+				return false
+			}
+			g.any = append(g.any, t)
+			return true
+		}
+	}
+	return false
+}
+
+// splitExtra returns the single un-negated term of a that is not in b and the
+// remaining terms of a, if a and b differ by exactly that one term.
+func splitExtra(a, b conj) (term, conj, bool) {
+	var extra []term
+	rest := make(conj, 0, len(a))
+	for _, t := range a {
+		if b.has(t) {
+			rest = append(rest, t)
+		} else {
+			extra = append(extra, t)
+		}
+	}
+	if len(extra) != 1 || extra[0].negate {
+		return term{}, nil, false
+	}
+	return extra[0], rest, true
+}
+
+// equal reports whether t and o are the same, possibly negated, host
+// predicate.
+func (t term) equal(o term) bool {
+	if t.negate != o.negate || t.op != o.op || t.path != o.path || t.arg != o.arg || t.num != o.num || len(t.values) != len(o.values) {
+		return false
+	}
+	for i := range t.values {
+		a, b := valueOf(t.values[i]), valueOf(o.values[i])
+		if a.Kind() != b.Kind() || !scalarEqual(a, b) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package tangent_sdk
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var exprTestDocs = []string{
+	`{}`,
+	`{"a":1}`,
+	`{"a":2,"b":"x"}`,
+	`{"a":2.5,"b":"y","c":true}`,
+	`{"a":"1","b":"xy","c":false}`,
+	`{"a":-1,"b":"p-1","c":true}`,
+	`{"b":null,"c":true}`,
+}
+
+// selectorsMatch reports whether any of sels matches l.
+func selectorsMatch(sels []Selector, l Log) bool {
+	return MatchAny(sels, l) >= 0
+}
+
+func TestSelectors(t *testing.T) {
+	logs := testLogs(t, exprTestDocs...)
+	tests := []struct {
+		src  string
+		sels int // expected number of selectors
+	}{
+		{`a == 1`, 1},
+		{`a == 1 || a == 2`, 1},
+		{`(a == 1 || a == 2) && has(c)`, 1},
+		{`a == 1 || b == "x"`, 1},
+		{`!(a == 1 && b == "x")`, 2},
+		{`a == 1 && a == 2`, 1},
+		{`has(a) || !has(a)`, 2},
+		{`has(a) && !has(a)`, 0},
+		{`(a == 1 || b == "x") && (c == true || b == "y")`, 2},
+		{`a < 2 || between(a, 2, 3)`, 2},
+		{`!(a < 0) && prefix(b, "x")`, 2},
+		{`a == 1 || (a == 1 && c == true)`, 1},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sels := Selectors(e)
+		if len(sels) != tt.sels {
+			t.Errorf("Selectors(%s) = %v, want %d selectors", tt.src, sels, tt.sels)
+		}
+		for i, l := range logs {
+			if got, want := selectorsMatch(sels, l), e.matches(l); got != want {
+				t.Errorf("Selectors(%s) = %v matches %s: %t, expression: %t", tt.src, sels, exprTestDocs[i], got, want)
+			}
+		}
+	}
+}
+
+func TestSelectorsRandom(t *testing.T) {
+	logs := testLogs(t, exprTestDocs...)
+	preds := []Predicate{
+		Has("a"), Has("c"), EqInt("a", 1), EqFloat("a", 2.5), EqString("b", "x"),
+		Prefix("b", "x"), Regex("b", "^p-"), InInts("a", 1, 2), EqBool("c", true),
+		Gt("a", 1), Lte("a", 2), Between("a", 0, 2),
+	}
+	rng := rand.New(rand.NewSource(1))
+	var gen func(depth int) Expr
+	gen = func(depth int) Expr {
+		if depth == 0 || rng.Intn(3) == 0 {
+			return preds[rng.Intn(len(preds))]
+		}
+		switch rng.Intn(3) {
+		case 0:
+			return Not(gen(depth - 1))
+		case 1:
+			return And(gen(depth-1), gen(depth-1))
+		default:
+			return Or(gen(depth-1), gen(depth-1))
+		}
+	}
+	for i := 0; i < 500; i++ {
+		e := gen(4)
+		sels := Selectors(e)
+		for j, l := range logs {
+			if got, want := selectorsMatch(sels, l), e.matches(l); got != want {
+				t.Fatalf("case %d: Selectors = %v matches %s: %t, expression: %t", i, sels, exprTestDocs[j], got, want)
+			}
+		}
+	}
+}
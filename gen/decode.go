@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/imports"
+)
+
+const (
+	decodeFileName = "decode_generated.go"
+	sdkImportPath  = "github.com/telophasehq/tangent-sdk-go"
+)
+
+// findDecodeTargets returns the package's named struct types that carry
+// `tangent:"path"` field tags, sorted by name. Types with a hand-written
+// DecodeFromLog method are left alone; generated ones are stubs while
+// loading (see stubGenerated).
+func findDecodeTargets(pkg *packages.Package, stubs stubs) []*types.Named {
+	scope := pkg.Types.Scope()
+	var out []*types.Named
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok || !hasTangentTags(st) {
+			continue
+		}
+		if m := lookupMethod(named, "DecodeFromLog"); m != nil && !stubs.declares(m) {
+			continue
+		}
+		out = append(out, named)
+	}
+	return out
+}
+
+func lookupMethod(named *types.Named, name string) *types.Func {
+	for i := 0; i < named.NumMethods(); i++ {
+		if m := named.Method(i); m.Name() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func hasTangentTags(st *types.Struct) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("tangent")
+		if ok && tag != "-" {
+			return true
+		}
+		if !ok && st.Field(i).Embedded() {
+			if es, ok := st.Field(i).Type().Underlying().(*types.Struct); ok && hasTangentTags(es) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generateDecoders renders DecodeFromLog methods for targets, one file per
+// source directory.
+func generateDecoders(pkg *packages.Package, targets []*types.Named) (map[string][]byte, error) {
+	byDir := map[string][]*types.Named{}
+	for _, n := range targets {
+		dir := filepath.Dir(pkg.Fset.Position(n.Obj().Pos()).Filename)
+		byDir[dir] = append(byDir[dir], n)
+	}
+
+	out := map[string][]byte{}
+	for dir, named := range byDir {
+		g := &decodeGen{pkg: pkg.Types, imports: map[string]string{}}
+		for _, n := range named {
+			if err := g.method(n); err != nil {
+				return nil, err
+			}
+		}
+
+		var src bytes.Buffer
+		src.WriteString(header)
+		fmt.Fprintf(&src, "package %s\n\n", pkg.Name)
+		src.WriteString("import (\n\t\"strconv\"\n\n")
+		fmt.Fprintf(&src, "\ttangent_sdk %q\n", sdkImportPath)
+		paths := make([]string, 0, len(g.imports))
+		for p := range g.imports {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			if name := g.imports[p]; name != path.Base(p) {
+				fmt.Fprintf(&src, "\t%s %q\n", name, p)
+			} else {
+				fmt.Fprintf(&src, "\t%q\n", p)
+			}
+		}
+		src.WriteString(")\n\n")
+		src.Write(g.buf.Bytes())
+
+		formatted, err := imports.Process(decodeFileName, src.Bytes(), &imports.Options{
+			Comments:  true,
+			TabWidth:  8,
+			TabIndent: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("format decoders: %w", err)
+		}
+		out[filepath.Join(dir, decodeFileName)] = formatted
+	}
+	return out, nil
+}
+
+// pathExpr is a Go string expression built from an optional dynamic part
+// (a variable holding a runtime prefix) followed by a literal suffix.
+type pathExpr struct {
+	dyn string
+	lit string
+}
+
+func (p pathExpr) String() string {
+	switch {
+	case p.dyn == "":
+		return strconv.Quote(p.lit)
+	case p.lit == "":
+		return p.dyn
+	default:
+		return p.dyn + " + " + strconv.Quote(p.lit)
+	}
+}
+
+// join appends a relative log path, mirroring tangent_sdk's joinPath.
+func (p pathExpr) join(rel string) pathExpr {
+	if p.dyn != "" && p.lit == "" {
+		if rel != "" && rel[0] != '[' {
+			rel = "." + rel
+		}
+		return pathExpr{dyn: p.dyn, lit: rel}
+	}
+	switch {
+	case p.lit == "":
+		p.lit = rel
+	case rel == "":
+	case rel[0] == '[':
+		p.lit += rel
+	default:
+		p.lit += "." + rel
+	}
+	return p
+}
+
+// index returns an expression for p followed by "[idx]", where idx names an
+// int variable.
+func (p pathExpr) index(idx string) string {
+	p.lit += "["
+	return fmt.Sprintf("%s + strconv.Itoa(%s) + \"]\"", p, idx)
+}
+
+// key returns an expression for p followed by ".key", where key names a
+// string variable.
+func (p pathExpr) key(key string) string {
+	p.lit += "."
+	return fmt.Sprintf("%s + %s", p, key)
+}
+
+// field appends a Go field name.
+func (p pathExpr) field(name string) pathExpr {
+	if p.dyn == "" && p.lit == "" {
+		return pathExpr{lit: name}
+	}
+	p.lit += "." + name
+	return p
+}
+
+type decodeGen struct {
+	pkg     *types.Package
+	buf     bytes.Buffer
+	imports map[string]string // import path -> package name
+	vars    int
+}
+
+func (g *decodeGen) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *decodeGen) next() int {
+	g.vars++
+	return g.vars
+}
+
+func (g *decodeGen) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+func (g *decodeGen) method(n *types.Named) error {
+	name := n.Obj().Name()
+	g.printf("// DecodeFromLog implements tangent_sdk.LogDecoder.")
+	g.printf("func (x *%s) DecodeFromLog(l tangent_sdk.Log) error {", name)
+	g.printf("var errs tangent_sdk.FieldErrors")
+	if err := g.structFields("x", n.Underlying().(*types.Struct), pathExpr{}, pathExpr{}); err != nil {
+		return fmt.Errorf("%s.%w", name, err)
+	}
+	g.printf("return errs.Err()")
+	g.printf("}\n")
+	return nil
+}
+
+func (g *decodeGen) structFields(lv string, st *types.Struct, prefix, owner pathExpr) error {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("tangent")
+		if tag == "-" {
+			continue
+		}
+		if !ok {
+			if es, ok := f.Type().(*types.Named); ok && f.Embedded() {
+				if inner, ok := es.Underlying().(*types.Struct); ok {
+					if err := g.structFields(lv+"."+f.Name(), inner, prefix, owner); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if !f.Exported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		err := g.field(lv+"."+f.Name(), f.Type(), prefix.join(name), owner.field(f.Name()), opts == "optional")
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (g *decodeGen) field(lv string, t types.Type, path, field pathExpr, optional bool) error {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return g.value(lv, t, path, field, optional, "")
+	}
+	elem := ptr.Elem()
+	if st, ok := structOf(elem); ok {
+		g.printf("if l.Has(%s) {", path)
+		g.printf("%s = new(%s)", lv, g.typeString(elem))
+		if err := g.structFields(lv, st, path, field); err != nil {
+			return err
+		}
+		g.printf("}")
+		return nil
+	}
+	tmp := fmt.Sprintf("t%d", g.next())
+	g.printf("{")
+	g.printf("var %s %s", tmp, g.typeString(elem))
+	if err := g.value(tmp, elem, path, field, true, fmt.Sprintf("%s = &%s", lv, tmp)); err != nil {
+		return err
+	}
+	g.printf("}")
+	return nil
+}
+
+// value fills lv from path. after, if set, runs once lv has been assigned.
+func (g *decodeGen) value(lv string, t types.Type, path, field pathExpr, optional bool, after string) error {
+	missing := func() {
+		if !optional {
+			g.printf("} else {")
+			g.printf("errs.Missing(%s, %s)", path, field)
+		}
+	}
+	assign := func(expr string) string {
+		if after == "" {
+			return fmt.Sprintf("%s = %s", lv, expr)
+		}
+		return fmt.Sprintf("%s = %s\n%s", lv, expr, after)
+	}
+
+	if st, ok := structOf(t); ok {
+		g.printf("if l.Has(%s) {", path)
+		if err := g.structFields(lv, st, path, field); err != nil {
+			return err
+		}
+		if after != "" {
+			g.printf("%s", after)
+		}
+		missing()
+		g.printf("}")
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			g.printf("if v, ok := l.Get(%s); ok {", path)
+			g.printf("if b, ok := v.AsBytes(); ok {")
+			g.printf("%s", assign(g.convert(t, "b")))
+			g.printf("} else if s, ok := v.AsString(); ok {")
+			g.printf("%s", assign(g.convert(t, "[]byte(s)")))
+			g.printf("} else {")
+			g.printf("errs.WrongKind(%s, %s, \"bytes\", v.Kind())", path, field)
+			g.printf("}")
+			missing()
+			g.printf("}")
+			return nil
+		}
+
+		n := g.next()
+		idx := fmt.Sprintf("j%d", n)
+		if _, isPtr := u.Elem().(*types.Pointer); isPtr || isStructType(u.Elem()) {
+			g.printf("if n := l.Len(%s); n != nil {", path)
+			g.printf("%s = make(%s, *n)", lv, g.typeString(t))
+			g.printf("for %s := range %s {", idx, lv)
+			g.printf("p%d := %s", n, path.index(idx))
+			g.printf("f%d := %s", n, field.index(idx))
+			elemPath, elemField := pathExpr{dyn: fmt.Sprintf("p%d", n)}, pathExpr{dyn: fmt.Sprintf("f%d", n)}
+			if err := g.field(fmt.Sprintf("%s[%s]", lv, idx), u.Elem(), elemPath, elemField, false); err != nil {
+				return err
+			}
+			g.printf("}")
+			if after != "" {
+				g.printf("%s", after)
+			}
+			missing()
+			g.printf("}")
+			return nil
+		}
+
+		g.printf("if vs, ok := l.GetList(%s); ok {", path)
+		g.printf("%s = make(%s, len(vs))", lv, g.typeString(t))
+		g.printf("for %s, v := range vs {", idx)
+		if err := g.scalar(fmt.Sprintf("%s[%s]", lv, idx), u.Elem(), "v", path.index(idx), field.String()); err != nil {
+			return err
+		}
+		g.printf("}")
+		if after != "" {
+			g.printf("%s", after)
+		}
+		missing()
+		g.printf("}")
+		return nil
+
+	case *types.Map:
+		if k, ok := u.Key().Underlying().(*types.Basic); !ok || k.Kind() != types.String {
+			return fmt.Errorf("map key must be a string, got %s", u.Key())
+		}
+		n := g.next()
+		m := fmt.Sprintf("m%d", n)
+		g.printf("if fs, ok := l.GetMap(%s); ok {", path)
+		g.printf("%s := make(%s, len(fs))", m, g.typeString(t))
+		g.printf("for _, fld := range fs {")
+		key := fmt.Sprintf("%s[%s]", m, g.convert(u.Key(), "fld.Key"))
+		if err := g.scalar(key, u.Elem(), "fld.Value", path.key("fld.Key"), field.String()); err != nil {
+			return err
+		}
+		g.printf("}")
+		g.printf("%s", assign(m))
+		missing()
+		g.printf("}")
+		return nil
+	}
+
+	g.printf("if v, ok := l.Get(%s); ok {", path)
+	if err := g.scalar(lv, t, "v", path.String(), field.String()); err != nil {
+		return err
+	}
+	if after != "" {
+		g.printf("%s", after)
+	}
+	missing()
+	g.printf("}")
+	return nil
+}
+
+// scalar converts the tangent_sdk.Value expression v into lv, reporting kind
+// mismatches against path and field.
+func (g *decodeGen) scalar(lv string, t types.Type, v, path, field string) error {
+	if isValueType(t) {
+		g.printf("%s = %s", lv, v)
+		return nil
+	}
+	if isTimeType(t) {
+		g.printf("if ts, f := tangent_sdk.ParseTime(%s); f != tangent_sdk.TimeUnknown {", v)
+		g.printf("%s = ts", lv)
+		g.printf("} else {")
+		g.printf("errs.NotTime(%s, %s, %s)", path, field, v)
+		g.printf("}")
+		return nil
+	}
+	if iface, ok := t.Underlying().(*types.Interface); ok {
+		if !iface.Empty() {
+			return fmt.Errorf("unsupported interface type %s", t)
+		}
+		g.printf("if a := %s.Interface(); a != nil {", v)
+		g.printf("%s = a", lv)
+		g.printf("}")
+		return nil
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	typ := g.typeString(t)
+	switch info := b.Info(); {
+	case b.Kind() == types.String:
+		g.printf("if s, ok := %s.AsString(); ok {", v)
+		g.printf("%s = %s", lv, g.convert(t, "s"))
+		g.printf("} else {")
+		g.printf("errs.WrongKind(%s, %s, \"string\", %s.Kind())", path, field, v)
+		g.printf("}")
+	case b.Kind() == types.Bool:
+		g.printf("if b, ok := %s.AsBool(); ok {", v)
+		g.printf("%s = %s", lv, g.convert(t, "b"))
+		g.printf("} else {")
+		g.printf("errs.WrongKind(%s, %s, \"bool\", %s.Kind())", path, field, v)
+		g.printf("}")
+	case info&types.IsInteger != 0:
+		g.printf("if i, ok := %s.AsInt(); !ok {", v)
+		g.printf("errs.WrongKind(%s, %s, \"int\", %s.Kind())", path, field, v)
+		if check := overflowCheck(b); check != "" {
+			g.printf("} else if %s {", check)
+			g.printf("errs.Overflow(%s, %s, i, %q)", path, field, typ)
+		}
+		g.printf("} else {")
+		g.printf("%s = %s", lv, g.convert(t, "i"))
+		g.printf("}")
+	case info&types.IsFloat != 0:
+		g.printf("if f, ok := %s.AsFloat(); ok {", v)
+		g.printf("%s = %s", lv, g.convert(t, "f"))
+		g.printf("} else if i, ok := %s.AsInt(); ok {", v)
+		g.printf("%s = %s", lv, g.convert(t, "i"))
+		g.printf("} else {")
+		g.printf("errs.WrongKind(%s, %s, \"float\", %s.Kind())", path, field, v)
+		g.printf("}")
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+// convert returns expr converted to t, omitting identity conversions.
+func (g *decodeGen) convert(t types.Type, expr string) string {
+	switch t := t.(type) {
+	case *types.Basic:
+		if t.Kind() == types.String || t.Kind() == types.Bool || t.Kind() == types.Int64 && expr == "i" || t.Kind() == types.Float64 && expr == "f" {
+			return expr
+		}
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			return expr
+		}
+	}
+	return fmt.Sprintf("%s(%s)", g.typeString(t), expr)
+}
+
+// overflowCheck returns a condition on the int64 i that is true when i does
+// not fit b.
+func overflowCheck(b *types.Basic) string {
+	switch b.Kind() {
+	case types.Int64:
+		return ""
+	case types.Int, types.Int8, types.Int16, types.Int32:
+		return fmt.Sprintf("int64(%s(i)) != i", b.Name())
+	case types.Uint64, types.Uintptr:
+		return "i < 0"
+	default:
+		return fmt.Sprintf("i < 0 || uint64(%s(i)) != uint64(i)", b.Name())
+	}
+}
+
+func structOf(t types.Type) (*types.Struct, bool) {
+	if isValueType(t) || isTimeType(t) {
+		return nil, false
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	return st, ok
+}
+
+func isStructType(t types.Type) bool {
+	_, ok := structOf(t)
+	return ok
+}
+
+func isValueType(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == "Value" && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == sdkImportPath
+}
+
+func isTimeType(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == "Time" && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time"
+}
+
+func isByte(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
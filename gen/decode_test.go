@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+// decodeTypes declares tagged structs exercising each decoder path. The
+// test module compiles it twice: in package m, which gen gives decoders,
+// and in package ref, which is left to reflection.
+const decodeTypes = `
+import (
+	"time"
+
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+)
+
+type Level string
+
+type Resource struct {
+	ID   string ` + "`tangent:\"id\"`" + `
+	Zone string ` + "`tangent:\"placement.zone,optional\"`" + `
+}
+
+type Base struct {
+	Source string ` + "`tangent:\"source\"`" + `
+}
+
+type Finding struct {
+	Base
+	Company   string              ` + "`tangent:\"detail.findings[0].CompanyName\"`" + `
+	Level     Level               ` + "`tangent:\"detail.level,optional\"`" + `
+	Severity  *float64            ` + "`tangent:\"detail.severity\"`" + `
+	Count     *int                ` + "`tangent:\"detail.count\"`" + `
+	Small     int8                ` + "`tangent:\"detail.small,optional\"`" + `
+	OK        bool                ` + "`tangent:\"detail.ok,optional\"`" + `
+	Tags      []string            ` + "`tangent:\"detail.tags\"`" + `
+	Ports     []uint16            ` + "`tangent:\"detail.ports,optional\"`" + `
+	Labels    map[string]string   ` + "`tangent:\"labels,optional\"`" + `
+	Scores    map[string]float64  ` + "`tangent:\"scores,optional\"`" + `
+	Resource  Resource            ` + "`tangent:\"detail.resource\"`" + `
+	Resources []Resource          ` + "`tangent:\"detail.resources,optional\"`" + `
+	Refs      []*Resource         ` + "`tangent:\"detail.refs,optional\"`" + `
+	Owner     *Resource           ` + "`tangent:\"detail.owner\"`" + `
+	Raw       tangent_sdk.Value   ` + "`tangent:\"detail.findings[0].CompanyName,optional\"`" + `
+	Any       any                 ` + "`tangent:\"detail.any,optional\"`" + `
+	Data      []byte              ` + "`tangent:\"detail.data,optional\"`" + `
+	At        time.Time           ` + "`tangent:\"at,optional\"`" + `
+	Seen      *time.Time          ` + "`tangent:\"seen\"`" + `
+	Times     []time.Time         ` + "`tangent:\"times,optional\"`" + `
+	Ignored   string
+	Skipped   string ` + "`tangent:\"-\"`" + `
+}
+`
+
+const decodeTest = `package m
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+	"github.com/telophasehq/tangent-sdk-go/tangenttest"
+
+	"example.com/m/ref"
+)
+
+var docs = []string{
+	"{}",
+	` + "`" + `{"source":"s","detail":{"findings":[{"CompanyName":"c"}],"tags":[],"resource":{"id":"r"}}}` + "`" + `,
+	` + "`" + `{
+		"source": "scanner", "at": "2024-01-02T03:04:05Z", "seen": 1704164645000, "times": [1704164645, "2024-01-02"],
+		"detail": {
+			"findings": [{"CompanyName": "Acme"}], "level": "high", "severity": 7, "count": 3, "small": -8, "ok": true,
+			"tags": ["a", "b"], "ports": [22, 443], "any": {"k": [1, "s"]}, "data": "AQID",
+			"resource": {"id": "r-1", "placement": {"zone": "us-east-1a"}},
+			"resources": [{"id": "r-2"}, {"id": "r-3", "placement": {"zone": "z"}}],
+			"refs": [{"id": "r-4"}], "owner": {"id": "o"}
+		},
+		"labels": {"env": "prod", "team": "core"}, "scores": {"a": 1, "b": 2.5},
+		"Ignored": "x", "Skipped": "x"
+	}` + "`" + `,
+	` + "`" + `{
+		"at": "yesterday", "seen": true, "times": [false],
+		"detail": {
+			"findings": [{"CompanyName": 1}], "level": 2, "severity": "high", "count": 1.5, "small": 300, "ok": "yes",
+			"tags": ["a", 2, "c", false], "ports": [80, 70000, -1], "data": 5,
+			"resource": {"placement": {}}, "resources": [{"id": "ok"}, {}], "refs": [{}, {"id": 1}],
+			"owner": {"id": true}
+		},
+		"labels": {"env": "prod", "n": 3}, "scores": {"a": "x"}
+	}` + "`" + `,
+}
+
+// result renders a decoded value and its error for comparison.
+func result(v any, err error) string {
+	b, jerr := json.Marshal(v)
+	if jerr != nil {
+		panic(jerr)
+	}
+	return fmt.Sprintf("%s\nerr: %v", b, err)
+}
+
+func TestGeneratedDecodersMatchReflection(t *testing.T) {
+	var _ tangent_sdk.LogDecoder = (*Finding)(nil)
+	for _, doc := range docs {
+		l := tangenttest.MustLog(doc)
+		var v Finding
+		var r ref.Finding
+		gerr := tangent_sdk.Decode(l, &v)
+		rerr := tangent_sdk.Decode(l, &r)
+		got, want := result(v, gerr), result(r, rerr)
+		if got != want {
+			t.Errorf("doc %s:\ngenerated  %s\nreflection %s", doc, got, want)
+		}
+	}
+}
+`
+
+func TestGeneratedDecodersMatchReflection(t *testing.T) {
+	dir := tempModule(t, map[string]string{
+		"types.go":     "package m\n" + decodeTypes,
+		"ref/types.go": "package ref\n" + decodeTypes,
+		"m_test.go":    decodeTest,
+	})
+	if err := run(dir, []string{"."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	goCmd(t, dir, "test", "./...")
+
+	if err := run(dir, []string{"."}, "json_generated.go", true, io.Discard); err != nil {
+		t.Errorf("-check after generating: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// emitter writes generated files. With check set it leaves the tree alone
+// and instead records a diff for every file that is out of date.
+type emitter struct {
+	check   bool
+	diffs   []string
+	written map[string]bool // paths passed to write
+}
+
+func (e *emitter) write(path string, b []byte) error {
+	e.written[path] = true
+	if !e.check {
+		return os.WriteFile(path, b, 0o644)
+	}
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !bytes.Equal(old, b) {
+		e.diffs = append(e.diffs, unifiedDiff(displayPath(path), old, b))
+	}
+	return nil
+}
+
+// remove deletes a generated file that is no longer needed, if it exists.
+func (e *emitter) remove(path string) error {
+	if !e.check {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	old, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	e.diffs = append(e.diffs, unifiedDiff(displayPath(path), old, nil))
+	return nil
+}
+
+// displayPath shortens path to be relative to the working directory when it
+// is below it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// unifiedDiff renders the change from old to new in unified diff format.
+func unifiedDiff(name string, old, new []byte) string {
+	a, b := splitLines(old), splitLines(new)
+
+	// Lines shared at both ends are trimmed before the quadratic LCS;
+	// regenerated files usually differ in a few places.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < pre; i++ {
+		ops = append(ops, diffOp{' ', a[i]})
+	}
+	ops = append(ops, lcsDiff(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for i := len(a) - suf; i < len(a); i++ {
+		ops = append(ops, diffOp{' ', a[i]})
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (generated)\n", name, name)
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+		// Grow the hunk while changes are within 2*diffContext lines of
+		// each other.
+		start := max(i-diffContext, 0)
+		end := i
+		for gap := 0; end < len(ops) && gap <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				gap++
+			} else {
+				gap = 0
+			}
+		}
+		for end > i && ops[end-1].kind == ' ' {
+			end--
+		}
+		end = min(end+diffContext, len(ops))
+
+		oldStart, newStart := oldLine-(i-start), newLine-(i-start)
+		var oldN, newN int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldN++
+			}
+			if op.kind != '-' {
+				newN++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldN), hunkRange(newStart, newN))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		for _, op := range ops[i:end] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start-- // an empty range names the line before it
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// lcsDiff returns the edit script turning a into b, keeping a longest
+// common subsequence of lines.
+func lcsDiff(a, b []string) []diffOp {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// splitLines splits b into lines, each keeping its trailing newline.
+func splitLines(b []byte) []string {
+	var lines []string
+	for len(b) > 0 {
+		n := bytes.IndexByte(b, '\n') + 1
+		if n == 0 {
+			n = len(b)
+		}
+		lines = append(lines, string(b[:n]))
+		b = b[n:]
+	}
+	return lines
+}
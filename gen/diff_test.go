@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	// numbered returns lines "l1".."ln", replacing those in edits.
+	numbered := func(n int, edits map[int]string) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			if line, ok := edits[i]; ok {
+				b.WriteString(line + "\n")
+			} else {
+				fmt.Fprintf(&b, "l%d\n", i)
+			}
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"insert", "a\nb\nc\n", "a\nx\nb\nc\n", "@@ -1,3 +1,4 @@\n a\n+x\n b\n c\n"},
+		{"delete", "a\nx\nb\nc\n", "a\nb\nc\n", "@@ -1,4 +1,3 @@\n a\n-x\n b\n c\n"},
+		{"change", "a\nb\nc\nd\n", "a\nB\nc\nd\n", "@@ -1,4 +1,4 @@\n a\n-b\n+B\n c\n d\n"},
+		{"empty old", "", "a\nb\n", "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"empty new", "a\nb\n", "", "@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{"single line", "a\n", "b\n", "@@ -1 +1 @@\n-a\n+b\n"},
+		{"no trailing newline", "a\nb\nc\n", "a\nb", "@@ -1,3 +1,2 @@\n a\n-b\n-c\n+b\n\\ No newline at end of file\n"},
+		{"trailing newline added", "a\nb", "a\nb\n", "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+		{
+			"context trimmed",
+			numbered(10, nil), numbered(10, map[int]string{5: "x"}),
+			"@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+x\n l6\n l7\n l8\n",
+		},
+		{
+			"two hunks",
+			numbered(20, nil), numbered(20, map[int]string{2: "x", 19: "y"}),
+			"@@ -1,5 +1,5 @@\n l1\n-l2\n+x\n l3\n l4\n l5\n" +
+				"@@ -16,5 +16,5 @@\n l16\n l17\n l18\n-l19\n+y\n l20\n",
+		},
+		{
+			"changes seven lines apart split",
+			numbered(13, nil), numbered(13, map[int]string{2: "x", 10: "y"}),
+			"@@ -1,5 +1,5 @@\n l1\n-l2\n+x\n l3\n l4\n l5\n" +
+				"@@ -7,7 +7,7 @@\n l7\n l8\n l9\n-l10\n+y\n l11\n l12\n l13\n",
+		},
+		{
+			"changes six lines apart share a hunk",
+			numbered(12, nil), numbered(12, map[int]string{2: "x", 9: "y"}),
+			"@@ -1,12 +1,12 @@\n l1\n-l2\n+x\n l3\n l4\n l5\n l6\n l7\n l8\n-l9\n+y\n l10\n l11\n l12\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := "--- f.go\n+++ f.go (generated)\n" + tt.want
+			if got := unifiedDiff("f.go", []byte(tt.old), []byte(tt.new)); got != want {
+				t.Errorf("unifiedDiff(%q, %q) =\n%s\nwant\n%s", tt.old, tt.new, got, want)
+			}
+		})
+	}
+}
+
+func TestLCSDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string // ops as kind+line, joined by |
+	}{
+		{"", "", ""},
+		{"ab", "ab", " a| b"},
+		{"", "ab", "+a|+b"},
+		{"ab", "", "-a|-b"},
+		{"abc", "axc", " a|-b|+x| c"},
+		{"abcd", "acbd", " a|-b| c|+b| d"},
+	}
+	for _, tt := range tests {
+		var ops []string
+		for _, op := range lcsDiff(strings.Split(tt.a, "")[:len(tt.a)], strings.Split(tt.b, "")[:len(tt.b)]) {
+			ops = append(ops, string(op.kind)+op.line)
+		}
+		if got := strings.Join(ops, "|"); got != tt.want {
+			t.Errorf("lcsDiff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	tests := []struct {
+		start, n int
+		want     string
+	}{
+		{1, 0, "0,0"},
+		{5, 0, "4,0"},
+		{1, 1, "1"},
+		{7, 1, "7"},
+		{1, 3, "1,3"},
+		{10, 12, "10,12"},
+	}
+	for _, tt := range tests {
+		if got := hunkRange(tt.start, tt.n); got != tt.want {
+			t.Errorf("hunkRange(%d, %d) = %q, want %q", tt.start, tt.n, got, tt.want)
+		}
+	}
+}
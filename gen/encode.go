@@ -0,0 +1,671 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/imports"
+)
+
+const (
+	jwriterImportPath  = "github.com/mailru/easyjson/jwriter"
+	easyjsonImportPath = "github.com/mailru/easyjson"
+)
+
+// encodeGen renders marshallers for output types from their go/types
+// description. easyjson's own generator can only describe types compiled
+// into the running program (it walks reflect.Types), so gen reproduces its
+// encoder here instead: the same json tag handling, marshaler precedence and
+// jwriter calls, as MarshalEasyJSON and MarshalJSON methods plus one encoder
+// function per struct type.
+type encodeGen struct {
+	pkg        *types.Package
+	buf        bytes.Buffer
+	imports    map[string]string // import path -> package name
+	vars       int
+	local      map[*types.Named]bool // package types given methods in this file
+	marshalled map[string]bool       // types given marshallers in their own package, by qualified name
+	encoders   typeutil.Map          // struct type -> encoder function name
+	queue      []types.Type          // encoders still to render
+	names      map[string]bool       // encoder function names in use
+	stubs      stubs                 // methods to disregard
+}
+
+// generateMarshallers renders the marshaller file for pkg: methods for the
+// local output types and the package types they use, and for the wrapper
+// types standing in for external output types.
+func generateMarshallers(pkg *packages.Package, local []*types.Named, external []*types.Named, wrappers []string, marshalled map[string]bool, stubs stubs) ([]byte, error) {
+	g := &encodeGen{
+		pkg:        pkg.Types,
+		imports:    map[string]string{},
+		local:      map[*types.Named]bool{},
+		marshalled: marshalled,
+		stubs:      stubs,
+		names:      map[string]bool{},
+	}
+	// Output types with a MarshalJSON or MarshalText of their own still
+	// need MarshalEasyJSON, which calls it.
+	var roots []*types.Named
+	withMethods := map[*types.Named]bool{}
+	for _, n := range local {
+		if !g.hasMethod(n, "MarshalEasyJSON") {
+			roots = append(roots, n)
+			withMethods[n] = true
+		}
+	}
+	g.collect(roots)
+
+	for n := range g.local {
+		withMethods[n] = true
+	}
+	var named []*types.Named
+	for n := range withMethods {
+		named = append(named, n)
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Obj().Name() < named[j].Obj().Name() })
+	for _, n := range named {
+		if err := g.methods(n.Obj().Name(), n, "v"); err != nil {
+			return nil, fmt.Errorf("%s: %w", n.Obj().Name(), err)
+		}
+	}
+	for i, n := range external {
+		if err := g.methods(wrappers[i], n, g.typeString(n)+"(v)"); err != nil {
+			return nil, fmt.Errorf("%s: %w", n, err)
+		}
+	}
+	for len(g.queue) > 0 {
+		t := g.queue[0]
+		g.queue = g.queue[1:]
+		if err := g.structEncoder(t); err != nil {
+			return nil, fmt.Errorf("%s: %w", types.TypeString(t, types.RelativeTo(g.pkg)), err)
+		}
+	}
+
+	var src bytes.Buffer
+	src.WriteString(header)
+	fmt.Fprintf(&src, "package %s\n\n", pkg.Name)
+	src.WriteString("import (\n")
+	fmt.Fprintf(&src, "\tjwriter %q\n", jwriterImportPath)
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if name := g.imports[p]; name != path.Base(p) {
+			fmt.Fprintf(&src, "\t%s %q\n", name, p)
+		} else {
+			fmt.Fprintf(&src, "\t%q\n", p)
+		}
+	}
+	src.WriteString(")\n\n")
+	src.Write(g.buf.Bytes())
+
+	formatted, err := imports.Process("marshal.go", src.Bytes(), &imports.Options{
+		Comments:  true,
+		TabWidth:  8,
+		TabIndent: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("format marshallers: %w", err)
+	}
+	return formatted, nil
+}
+
+func (g *encodeGen) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *encodeGen) next() int {
+	g.vars++
+	return g.vars
+}
+
+func (g *encodeGen) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// addLocal marks n as given methods here if it is a struct declared in the
+// package without marshalling methods of its own.
+func (g *encodeGen) addLocal(n *types.Named) bool {
+	if g.local[n] || n.Obj().Pkg() != g.pkg || n.TypeArgs().Len() > 0 {
+		return false
+	}
+	if _, ok := n.Underlying().(*types.Struct); !ok || g.customMarshaler(n) != "" {
+		return false
+	}
+	g.local[n] = true
+	return true
+}
+
+// collect adds the package's struct types reachable from roots through
+// fields, so nested types marshal on their own as well.
+func (g *encodeGen) collect(roots []*types.Named) {
+	seen := map[types.Type]bool{}
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t := t.(type) {
+		case *types.Named:
+			if t.Obj().Pkg() == g.pkg && g.customMarshaler(t) == "" {
+				g.addLocal(t)
+				visit(t.Underlying())
+			}
+		case *types.Pointer:
+			visit(t.Elem())
+		case *types.Slice:
+			visit(t.Elem())
+		case *types.Array:
+			visit(t.Elem())
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				visit(t.Field(i).Type())
+			}
+		}
+	}
+	for _, n := range roots {
+		if g.addLocal(n) {
+			visit(n.Underlying())
+		}
+	}
+}
+
+// methods renders MarshalEasyJSON and MarshalJSON for the type recv, which
+// marshals as t. conv converts the receiver v to t.
+func (g *encodeGen) methods(recv string, t *types.Named, conv string) error {
+	g.printf("// MarshalEasyJSON implements easyjson.Marshaler.")
+	g.printf("func (v %s) MarshalEasyJSON(out *jwriter.Writer) {", recv)
+	in := "v"
+	if conv != "v" {
+		g.printf("in := %s", conv)
+		in = "in"
+	}
+	if err := g.encode(t, in, fieldTags{}, false); err != nil {
+		return err
+	}
+	g.printf("}\n")
+	if recv == t.Obj().Name() && g.hasMethod(t, "MarshalJSON") {
+		return nil
+	}
+	g.printf("// MarshalJSON implements json.Marshaler.")
+	g.printf("func (v %s) MarshalJSON() ([]byte, error) {", recv)
+	g.printf("w := jwriter.Writer{}")
+	g.printf("v.MarshalEasyJSON(&w)")
+	g.printf("return w.Buffer.BuildBytes(), w.Error")
+	g.printf("}\n")
+	return nil
+}
+
+// hasMethod reports whether t or *t has the method name, other than a stub.
+func (g *encodeGen) hasMethod(t types.Type, name string) bool {
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, nil, name)
+	fn, ok := obj.(*types.Func)
+	return ok && !g.stubs.declares(fn)
+}
+
+// customMarshaler returns the marshalling method easyjson would call for t
+// in preference to encoding it field by field, or "".
+func (g *encodeGen) customMarshaler(t types.Type) string {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil && n.Obj().Pkg() != g.pkg && g.marshalled[n.Obj().Pkg().Path()+"."+n.Obj().Name()] {
+		return "MarshalEasyJSON"
+	}
+	for _, m := range []string{"MarshalEasyJSON", "MarshalJSON", "MarshalText"} {
+		if g.hasMethod(t, m) {
+			return m
+		}
+	}
+	return ""
+}
+
+// fieldTags is a parsed json struct tag.
+type fieldTags struct {
+	name        string
+	omit        bool
+	omitEmpty   bool
+	noOmitEmpty bool
+	asString    bool
+}
+
+func parseFieldTags(tag string) fieldTags {
+	var ret fieldTags
+	opts := reflect.StructTag(tag).Get("json")
+	if opts == "-" {
+		// Unlike easyjson, "-," names the field "-", as in encoding/json.
+		ret.omit = true
+		return ret
+	}
+	for i, s := range strings.Split(opts, ",") {
+		switch {
+		case i == 0:
+			ret.name = s
+		case s == "omitempty":
+			ret.omitEmpty = true
+		case s == "!omitempty":
+			ret.noOmitEmpty = true
+		case s == "string":
+			ret.asString = true
+		}
+	}
+	return ret
+}
+
+var primitiveEncoders = map[types.BasicKind]string{
+	types.String:  "out.String(string(%s))",
+	types.Bool:    "out.Bool(bool(%s))",
+	types.Int:     "out.Int(int(%s))",
+	types.Int8:    "out.Int8(int8(%s))",
+	types.Int16:   "out.Int16(int16(%s))",
+	types.Int32:   "out.Int32(int32(%s))",
+	types.Int64:   "out.Int64(int64(%s))",
+	types.Uint:    "out.Uint(uint(%s))",
+	types.Uint8:   "out.Uint8(uint8(%s))",
+	types.Uint16:  "out.Uint16(uint16(%s))",
+	types.Uint32:  "out.Uint32(uint32(%s))",
+	types.Uint64:  "out.Uint64(uint64(%s))",
+	types.Uintptr: "out.Uint64(uint64(%s))",
+	types.Float32: "out.Float32(float32(%s))",
+	types.Float64: "out.Float64(float64(%s))",
+}
+
+var primitiveStringEncoders = map[types.BasicKind]string{
+	types.String:  "out.String(string(%s))",
+	types.Int:     "out.IntStr(int(%s))",
+	types.Int8:    "out.Int8Str(int8(%s))",
+	types.Int16:   "out.Int16Str(int16(%s))",
+	types.Int32:   "out.Int32Str(int32(%s))",
+	types.Int64:   "out.Int64Str(int64(%s))",
+	types.Uint:    "out.UintStr(uint(%s))",
+	types.Uint8:   "out.Uint8Str(uint8(%s))",
+	types.Uint16:  "out.Uint16Str(uint16(%s))",
+	types.Uint32:  "out.Uint32Str(uint32(%s))",
+	types.Uint64:  "out.Uint64Str(uint64(%s))",
+	types.Uintptr: "out.UintptrStr(uintptr(%s))",
+	types.Float32: "out.Float32Str(float32(%s))",
+	types.Float64: "out.Float64Str(float64(%s))",
+}
+
+// encode renders code writing in, of type t, to out. in must be
+// addressable, so that pointer-receiver marshalers can be called on it.
+func (g *encodeGen) encode(t types.Type, in string, tags fieldTags, assumeNonEmpty bool) error {
+	if n, ok := t.(*types.Named); !ok || !g.local[n] {
+		switch g.customMarshaler(t) {
+		case "MarshalEasyJSON":
+			g.printf("(%s).MarshalEasyJSON(out)", in)
+			return nil
+		case "MarshalJSON":
+			g.printf("out.Raw((%s).MarshalJSON())", in)
+			return nil
+		case "MarshalText":
+			g.printf("out.RawText((%s).MarshalText())", in)
+			return nil
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if enc := primitiveStringEncoders[u.Kind()]; enc != "" && tags.asString {
+			g.printf(enc, in)
+			return nil
+		}
+		if enc := primitiveEncoders[u.Kind()]; enc != "" {
+			g.printf(enc, in)
+			return nil
+		}
+
+	case *types.Slice:
+		if types.Identical(u.Elem(), types.Typ[types.Uint8]) {
+			g.printf("out.Base64Bytes(%s)", in)
+			return nil
+		}
+		n := g.next()
+		if assumeNonEmpty {
+			g.printf("{")
+		} else {
+			g.printf("if %s == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {", in)
+			g.printf("out.RawString(\"null\")")
+			g.printf("} else {")
+		}
+		g.printf("out.RawByte('[')")
+		g.printf("for i%d, v%d := range %s {", n, n, in)
+		g.printf("if i%d > 0 {", n)
+		g.printf("out.RawByte(',')")
+		g.printf("}")
+		if err := g.encode(u.Elem(), fmt.Sprintf("v%d", n), tags, false); err != nil {
+			return err
+		}
+		g.printf("}")
+		g.printf("out.RawByte(']')")
+		g.printf("}")
+		return nil
+
+	case *types.Array:
+		if types.Identical(u.Elem(), types.Typ[types.Uint8]) {
+			g.printf("out.Base64Bytes((%s)[:])", in)
+			return nil
+		}
+		n := g.next()
+		g.printf("out.RawByte('[')")
+		g.printf("for i%d := range %s {", n, in)
+		g.printf("if i%d > 0 {", n)
+		g.printf("out.RawByte(',')")
+		g.printf("}")
+		if err := g.encode(u.Elem(), fmt.Sprintf("(%s)[i%d]", in, n), tags, false); err != nil {
+			return err
+		}
+		g.printf("}")
+		g.printf("out.RawByte(']')")
+		return nil
+
+	case *types.Struct:
+		g.printf("%s(out, %s)", g.encoderName(t), in)
+		return nil
+
+	case *types.Pointer:
+		if !assumeNonEmpty {
+			g.printf("if %s == nil {", in)
+			g.printf("out.RawString(\"null\")")
+			g.printf("} else {")
+		}
+		if err := g.encode(u.Elem(), "*"+in, tags, false); err != nil {
+			return err
+		}
+		if !assumeNonEmpty {
+			g.printf("}")
+		}
+		return nil
+
+	case *types.Map:
+		return g.encodeMap(u, in, tags, assumeNonEmpty)
+
+	case *types.Interface:
+		switch {
+		case u.Empty():
+			g.imports[easyjsonImportPath] = "easyjson"
+			g.imports["encoding/json"] = "json"
+			g.printf("if m, ok := %s.(easyjson.Marshaler); ok {", in)
+			g.printf("m.MarshalEasyJSON(out)")
+			g.printf("} else if m, ok := %s.(json.Marshaler); ok {", in)
+			g.printf("out.Raw(m.MarshalJSON())")
+			g.printf("} else {")
+			g.printf("out.Raw(json.Marshal(%s))", in)
+			g.printf("}")
+			return nil
+		case interfaceHas(u, "MarshalEasyJSON"):
+			g.printf("%s.MarshalEasyJSON(out)", in)
+			return nil
+		case interfaceHas(u, "MarshalJSON"):
+			g.printf("out.Raw(%s.MarshalJSON())", in)
+			return nil
+		}
+		return fmt.Errorf("interface type %s not supported: only interface{} and interfaces with MarshalJSON or MarshalEasyJSON are", t)
+	}
+	return fmt.Errorf("don't know how to encode %s", t)
+}
+
+func (g *encodeGen) encodeMap(m *types.Map, in string, tags fieldTags, assumeNonEmpty bool) error {
+	key := m.Key()
+	kb, _ := key.Underlying().(*types.Basic)
+	var keyEnc string
+	if kb != nil {
+		keyEnc = primitiveStringEncoders[kb.Kind()]
+	}
+	if keyEnc == "" && g.customMarshaler(key) == "" {
+		return fmt.Errorf("map key type %s not supported: only string and integer keys and types implementing Marshaler interfaces are allowed", key)
+	}
+
+	n := g.next()
+	if assumeNonEmpty {
+		g.printf("{")
+	} else {
+		g.printf("if %s == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {", in)
+		g.printf("out.RawString(`null`)")
+		g.printf("} else {")
+	}
+	g.printf("out.RawByte('{')")
+	g.printf("first%d := true", n)
+	g.printf("for k%d, v%d := range %s {", n, n, in)
+	g.printf("if first%d {", n)
+	g.printf("first%d = false", n)
+	g.printf("} else {")
+	g.printf("out.RawByte(',')")
+	g.printf("}")
+	switch {
+	case g.hasMethod(key, "MarshalText"):
+		g.printf("out.RawBytesString((k%d).MarshalText())", n)
+	case keyEnc != "":
+		g.printf(keyEnc, fmt.Sprintf("k%d", n))
+	default:
+		if err := g.encode(key, fmt.Sprintf("k%d", n), tags, false); err != nil {
+			return err
+		}
+	}
+	g.printf("out.RawByte(':')")
+	if err := g.encode(m.Elem(), fmt.Sprintf("v%d", n), tags, false); err != nil {
+		return err
+	}
+	g.printf("}")
+	g.printf("out.RawByte('}')")
+	g.printf("}")
+	return nil
+}
+
+func interfaceHas(iface *types.Interface, name string) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		if iface.Method(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encoderName returns the name of the function encoding the struct type t,
+// queueing it to be rendered on first use.
+func (g *encodeGen) encoderName(t types.Type) string {
+	if name, ok := g.encoders.At(t).(string); ok {
+		return name
+	}
+	base := "tangentgenEncode"
+	if n, ok := t.(*types.Named); ok {
+		if p := n.Obj().Pkg(); p != nil && p != g.pkg {
+			base += strings.ToUpper(p.Name()[:1]) + p.Name()[1:]
+		}
+		base += strings.ToUpper(n.Obj().Name()[:1]) + n.Obj().Name()[1:]
+	} else {
+		base += "Struct"
+	}
+	name := base
+	for k := 2; g.names[name] || g.pkg.Scope().Lookup(name) != nil; k++ {
+		name = fmt.Sprintf("%s%d", base, k)
+	}
+	g.names[name] = true
+	g.encoders.Set(t, name)
+	g.queue = append(g.queue, t)
+	return name
+}
+
+// structEncoder renders the encoder function for the struct type t.
+func (g *encodeGen) structEncoder(t types.Type) error {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != g.pkg && !n.Obj().Exported() {
+		return fmt.Errorf("unexported type from another package")
+	}
+	g.printf("func %s(out *jwriter.Writer, in %s) {", g.encoders.At(t), g.typeString(t))
+	g.printf("out.RawByte('{')")
+	g.printf("first := true")
+	g.printf("_ = first")
+	firstCondition := true
+	for i, f := range structFields(t.Underlying().(*types.Struct), nil) {
+		var err error
+		firstCondition, err = g.structField(f, i == 0, firstCondition)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.v.Name(), err)
+		}
+	}
+	g.printf("out.RawByte('}')")
+	g.printf("}\n")
+	return nil
+}
+
+// encodeField is a struct field to encode, possibly promoted from an
+// embedded struct.
+type encodeField struct {
+	v    *types.Var
+	tags fieldTags
+	via  []string // embedded pointer fields on the way to v, outermost first
+}
+
+// structFields lists the fields of st that are encoded, as easyjson does:
+// exported fields, and the fields of untagged embedded structs, with a
+// field declared at an outer level hiding promoted ones of the same name.
+func structFields(st *types.Struct, via []string) []encodeField {
+	var embedded, fields []encodeField
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tags := parseFieldTags(st.Tag(i))
+		if !f.Embedded() || tags.name != "" {
+			continue
+		}
+		t := f.Type()
+		path := via
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+			path = append(append([]string(nil), via...), f.Name())
+		}
+		switch u := t.Underlying().(type) {
+		case *types.Struct:
+			embedded = mergeFields(embedded, structFields(u, path))
+		case *types.Basic:
+			if f.Exported() && u.Info()&types.IsComplex == 0 {
+				fields = append(fields, encodeField{v: f, tags: tags, via: via})
+			}
+		}
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tags := parseFieldTags(st.Tag(i))
+		if f.Embedded() && tags.name == "" {
+			continue
+		}
+		if f.Exported() {
+			fields = append(fields, encodeField{v: f, tags: tags, via: via})
+		}
+	}
+	return mergeFields(embedded, fields)
+}
+
+// mergeFields returns outer followed by the fields of inner that outer does
+// not hide.
+func mergeFields(inner, outer []encodeField) []encodeField {
+	used := map[string]bool{}
+	fields := append([]encodeField(nil), outer...)
+	for _, f := range outer {
+		used[f.v.Name()] = true
+	}
+	for _, f := range inner {
+		if !used[f.v.Name()] {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// structField renders one field of a struct encoder, following easyjson's
+// handling of the leading comma. It reports whether the fields after it
+// still need to check whether they are first.
+func (g *encodeGen) structField(f encodeField, first, firstCondition bool) (bool, error) {
+	if f.tags.omit {
+		return firstCondition, nil
+	}
+	jsonName := f.tags.name
+	if jsonName == "" {
+		jsonName = f.v.Name()
+	}
+	in := "in." + f.v.Name()
+
+	var conds []string
+	for _, e := range f.via {
+		conds = append(conds, "in."+e+" != nil")
+	}
+	noOmitEmpty := !f.tags.omitEmpty || f.tags.noOmitEmpty
+	if !noOmitEmpty {
+		conds = append(conds, g.notEmptyCheck(f.v.Type(), in))
+	}
+	toggleFirstCondition := firstCondition
+	if len(conds) == 0 {
+		g.printf("{")
+		toggleFirstCondition = false
+	} else {
+		g.printf("if %s {", strings.Join(conds, " && "))
+	}
+
+	g.printf("const prefix string = %q", ","+strconv.Quote(jsonName)+":")
+	switch {
+	case !firstCondition:
+		g.printf("out.RawString(prefix)")
+	case first:
+		if len(conds) > 0 {
+			g.printf("first = false")
+		}
+		g.printf("out.RawString(prefix[1:])")
+	default:
+		g.printf("if first {")
+		g.printf("first = false")
+		g.printf("out.RawString(prefix[1:])")
+		g.printf("} else {")
+		g.printf("out.RawString(prefix)")
+		g.printf("}")
+	}
+	if err := g.encode(f.v.Type(), in, f.tags, !noOmitEmpty); err != nil {
+		return toggleFirstCondition, err
+	}
+	g.printf("}")
+	return toggleFirstCondition, nil
+}
+
+// notEmptyCheck returns a condition that is false when v, of type t, is
+// empty for omitempty.
+func (g *encodeGen) notEmptyCheck(t types.Type, v string) string {
+	if g.hasMethod(t, "IsDefined") {
+		return "(" + v + ").IsDefined()"
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice, *types.Map:
+		return "len(" + v + ") != 0"
+	case *types.Interface, *types.Pointer:
+		return v + " != nil"
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsBoolean != 0:
+			return v
+		case info&types.IsString != 0:
+			return v + ` != ""`
+		case info&types.IsNumeric != 0:
+			return v + " != 0"
+		}
+	}
+	// Arrays and structs have no useful empty value.
+	return "true"
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// encodeTypes declares output types exercising each encoder path. The test
+// module compiles it twice: in package m, which gen gives marshallers, and
+// in package ref, which is left to encoding/json.
+const encodeTypes = `
+import (
+	"encoding/json"
+	"time"
+)
+
+type Level string
+
+type Base struct {
+	Host string ` + "`json:\"host\"`" + `
+	Port int    ` + "`json:\"port,omitempty\"`" + `
+}
+
+type Extra struct {
+	Note string ` + "`json:\"note\"`" + `
+}
+
+type Item struct {
+	Name  string  ` + "`json:\"name\"`" + `
+	Score float64 ` + "`json:\"score,omitempty\"`" + `
+}
+
+type Out struct {
+	Base
+	*Extra
+	ID       int64           ` + "`json:\"id,string\"`" + `
+	Level    Level           ` + "`json:\"level\"`" + `
+	OK       bool            ` + "`json:\"ok\"`" + `
+	Skip     string          ` + "`json:\"-\"`" + `
+	Dash     string          ` + "`json:\"-,\"`" + `
+	Untagged uint16
+	Ratio    float32         ` + "`json:\"ratio\"`" + `
+	Items    []Item          ` + "`json:\"items\"`" + `
+	Ptrs     []*Item         ` + "`json:\"ptrs,omitempty\"`" + `
+	ByName   map[string]Item ` + "`json:\"by_name\"`" + `
+	ByNum    map[int]string  ` + "`json:\"by_num\"`" + `
+	Raw      []byte          ` + "`json:\"raw\"`" + `
+	Fixed    [2]int          ` + "`json:\"fixed\"`" + `
+	When     time.Time       ` + "`json:\"when\"`" + `
+	Any      any             ` + "`json:\"any\"`" + `
+	Msg      json.RawMessage ` + "`json:\"msg,omitempty\"`" + `
+	Opt      *Item           ` + "`json:\"opt\"`" + `
+	Anon     struct {
+		A int ` + "`json:\"a\"`" + `
+	} ` + "`json:\"anon\"`" + `
+	private int
+}
+`
+
+const encodeTest = `package m
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"example.com/m/ref"
+)
+
+var docs = []string{
+	"{}",
+	` + "`" + `{"host":"h","port":8,"note":"n","id":"42","level":"warn","ok":true,"-":"dash","Untagged":7,
+	  "ratio":0.25,"items":[{"name":"a","score":1.5},{"name":"b"}],"ptrs":[null,{"name":"c"}],
+	  "by_name":{"x":{"name":"x"},"y":{"name":"y","score":2}},"by_num":{"1":"one","20":"twenty"},
+	  "raw":"AQID","fixed":[1,2],"when":"2024-05-06T07:08:09.5Z","any":{"k":[1,"s",null]},
+	  "msg":{"raw":true},"opt":{"name":"o"},"anon":{"a":3}}` + "`" + `,
+	` + "`" + `{"items":[],"by_name":{},"by_num":{},"raw":"","ptrs":[]}` + "`" + `,
+}
+
+func TestMarshalMatchesEncodingJSON(t *testing.T) {
+	for _, doc := range docs {
+		var v Out
+		var r ref.Out
+		if err := json.Unmarshal([]byte(doc), &v); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(doc), &r); err != nil {
+			t.Fatal(err)
+		}
+		got, err := v.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var g, w any
+		if err := json.Unmarshal(got, &g); err != nil {
+			t.Fatalf("generated output %s: %v", got, err)
+		}
+		if err := json.Unmarshal(want, &w); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("doc %s:\ngenerated     %s\nencoding/json %s", doc, got, want)
+		}
+	}
+}
+`
+
+func TestGeneratedMarshallersMatchEncodingJSON(t *testing.T) {
+	dir := tempModule(t, map[string]string{
+		"types.go":     "package m\n" + encodeTypes,
+		"ref/types.go": "package ref\n" + encodeTypes,
+		"wire.go": `package m
+
+import tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+func init() {
+	tangent_sdk.WireMulti(tangent_sdk.Metadata{Name: "m"}, nil, func(tangent_sdk.Log) ([]Out, error) { return nil, nil })
+}
+`,
+		"m_test.go": encodeTest,
+	})
+	if err := run(dir, []string{"./..."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	goCmd(t, dir, "test", "./...")
+
+	// Output is stable: a second run finds nothing to change.
+	if err := run(dir, []string{"./..."}, "json_generated.go", true, io.Discard); err != nil {
+		t.Errorf("-check after generating: %v", err)
+	}
+}
+
+func TestParseFieldTags(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want fieldTags
+	}{
+		{``, fieldTags{}},
+		{`json:"a"`, fieldTags{name: "a"}},
+		{`json:"-"`, fieldTags{omit: true}},
+		{`json:"-,"`, fieldTags{name: "-"}},
+		{`json:",omitempty"`, fieldTags{omitEmpty: true}},
+		{`json:"n,string,!omitempty" tangent:"x"`, fieldTags{name: "n", asString: true, noOmitEmpty: true}},
+	}
+	for _, tt := range tests {
+		if got := parseFieldTags(tt.tag); got != tt.want {
+			t.Errorf("parseFieldTags(%q) = %+v, want %+v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+// externalTest checks the marshaller registered for ext.Event, which gen
+// wraps because it can't add methods to another package's type.
+const externalTest = `package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+	"example.com/m/ext"
+)
+
+func TestExternalMarshaller(t *testing.T) {
+	ev := ext.Event{ID: 7, Tags: []string{"a"}, Item: &ext.Item{Name: "i"}, By: map[string]ext.Item{"k": {Name: "k"}}}
+	for _, v := range []any{ev, &ev, ext.Event{}, (*ext.Event)(nil)} {
+		var buf bytes.Buffer
+		if err := tangent_sdk.NDJSON.Encode(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.TrimSuffix(buf.Bytes(), []byte("\n")); !bytes.Equal(got, want) {
+			t.Errorf("NDJSON %s, encoding/json %s", got, want)
+		}
+	}
+}
+`
+
+func TestGeneratedExternalWrappers(t *testing.T) {
+	dir := tempModule(t, map[string]string{
+		"ext/ext.go": `package ext
+
+type Item struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type Event struct {
+	ID   int             ` + "`json:\"id\"`" + `
+	Tags []string        ` + "`json:\"tags,omitempty\"`" + `
+	Item *Item           ` + "`json:\"item\"`" + `
+	By   map[string]Item ` + "`json:\"by\"`" + `
+}
+`,
+		"main.go": `package main
+
+import (
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+	"example.com/m/ext"
+)
+
+func init() {
+	tangent_sdk.WireMulti(tangent_sdk.Metadata{Name: "ext"}, nil, func(tangent_sdk.Log) ([]ext.Event, error) { return nil, nil })
+}
+
+func main() {}
+`,
+		"main_test.go": externalTest,
+	})
+	if err := run(dir, []string{"./..."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"json_generated.go":          "package main",
+		"json_external_generated.go": "package main",
+	}
+	if got := readGenerated(t, dir); !maps.Equal(got, want) {
+		t.Errorf("generated %v, want %v", got, want)
+	}
+	goCmd(t, dir, "test", ".")
+}
+
+func TestWriteMarshallersPredeclared(t *testing.T) {
+	// Types without a package, like error, can't be wrapped; they must be
+	// reported rather than dereferenced.
+	named := types.NewNamed(types.NewTypeName(token.NoPos, nil, "T", nil), types.NewStruct(nil, nil), nil)
+	pkg := &packages.Package{PkgPath: "example.com/m", Fset: token.NewFileSet(), Types: types.NewPackage("example.com/m", "m")}
+	out := &emitter{check: true, written: map[string]bool{}}
+	err := writeMarshallers(pkg, map[*types.Named]*types.Struct{named: named.Underlying().(*types.Struct)}, out, "json_generated.go", nil, stubs{fset: pkg.Fset})
+	if err == nil || !strings.Contains(err.Error(), "output type T is predeclared") {
+		t.Errorf("writeMarshallers = %v", err)
+	}
+}
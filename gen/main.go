@@ -1,29 +1,28 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
-	"go/ast"
-	"go/printer"
+	"go/format"
 	"go/token"
 	"go/types"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
-	"golang.org/x/tools/imports"
 )
 
 const header = `// Code generated by tangentgen; DO NOT EDIT.
 
 `
 
-var pkgLineRx = regexp.MustCompile(`(?m)^package\s+([a-zA-Z_][a-zA-Z0-9_]*)\s*$`)
-
 type fieldSpec struct {
 	name      string // field name in output
 	goName    string // struct field ident
@@ -58,336 +57,409 @@ type genType struct {
 
 func main() {
 	log.SetFlags(0)
+	outName := flag.String("o", "json_generated.go", "name of the generated marshaller file in each package")
+	check := flag.Bool("check", false, "leave generated files untouched; print a diff of each that is out of date and exit 1 if any are")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gen [flags] [packages]\n\nPackages default to \".\"; patterns such as ./... are accepted.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	if err := run(".", patterns, *outName, *check, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
 
+// run generates code for the packages matching patterns, resolved in dir,
+// writing -check diffs to w.
+func run(dir string, patterns []string, outName string, check bool, w io.Writer) error {
+	if filepath.Base(outName) != outName || !strings.HasSuffix(outName, ".go") {
+		return fmt.Errorf("-o %s: must be a .go file name without a directory", outName)
+	}
+	// Files from a previous run are replaced by stubs while loading, so that
+	// stale output can neither break type checking nor feed back into what
+	// is generated.
+	listed, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles, Dir: dir}, patterns...)
+	if err != nil {
+		return fmt.Errorf("load: %w", err)
+	}
+	fset := token.NewFileSet()
+	overlay := stubGenerated(listed, outName)
 	cfg := &packages.Config{
 		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
-			packages.NeedCompiledGoFiles | packages.NeedTypes | packages.NeedTypesInfo,
-		Dir: ".",
+			packages.NeedCompiledGoFiles | packages.NeedTypes | packages.NeedTypesInfo |
+			packages.NeedImports | packages.NeedDeps,
+		Dir:     dir,
+		Fset:    fset,
+		Overlay: overlay,
 	}
-	pkgs, err := packages.Load(cfg, ".")
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		log.Fatalf("load: %v", err)
+		return fmt.Errorf("load: %w", err)
 	}
-	if packages.PrintErrors(pkgs) > 0 {
-		os.Exit(1)
+	if n := packages.PrintErrors(pkgs); n > 0 {
+		return fmt.Errorf("load: %d error(s)", n)
 	}
 	if len(pkgs) == 0 {
-		log.Fatalf("no packages found")
+		return errors.New("no packages found")
 	}
-	pkg := pkgs[0]
 
-	// Discover the output type by locating a call to tangent_sdk.Wire[T](...)
-	toGenerate := findWireOutputTypes(pkg)
-	if len(toGenerate) == 0 {
-		log.Fatalf("no Wire[T] instantiations found; pass -types or add a tangentgen shim")
+	var targets []*packages.Package
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		targets = append(targets, pkg)
 	}
-
-	err = runEasyJSONTypesOnly(pkg, toGenerate)
+	stubs := stubs{fset: fset, overlay: overlay}
+	marshalled, err := ownMarshallers(targets, stubs)
 	if err != nil {
-		log.Fatalf("easyjson generate failed: %v", err)
+		return err
 	}
-}
 
-// findWireOutputType searches the package's syntax for a call to
-// github.com/telophasehq/tangent-sdk-go.Wire[T](...) and returns the
-// name of T and its underlying struct type. Only named struct types are supported.
-func findWireOutputTypes(pkg *packages.Package) map[*types.Named]*types.Struct {
-	const tangentImportPath = "github.com/telophasehq/tangent-sdk-go"
-	out := map[*types.Named]*types.Struct{}
-	for _, f := range pkg.Syntax {
-		ast.Inspect(f, func(n ast.Node) bool {
-			call, ok := n.(*ast.CallExpr)
-			if !ok {
-				return true
-			}
-			switch fun := call.Fun.(type) {
-			case *ast.IndexExpr:
-				if !isTangentWire(pkg, fun.X, tangentImportPath) {
-					return true
-				}
-				if name, st := extractNamedStructType(pkg, fun.Index); st != nil {
-					out[name] = st
-				}
-			case *ast.IndexListExpr:
-				if !isTangentWire(pkg, fun.X, tangentImportPath) {
-					return true
-				}
-				if len(fun.Indices) > 0 {
-					if name, st := extractNamedStructType(pkg, fun.Indices[0]); st != nil {
-						out[name] = st
-					}
-				}
-			}
-			return true
-		})
+	out := &emitter{check: check, written: map[string]bool{}}
+	found := false
+	for _, pkg := range targets {
+		ok, err := generatePackage(pkg, out, outName, marshalled, stubs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pkg.PkgPath, err)
+		}
+		found = found || ok
+		if err := removeStale(pkg, out, outName); err != nil {
+			return fmt.Errorf("%s: %w", pkg.PkgPath, err)
+		}
 	}
-	return out
-}
-
-// isTangentWire reports whether expr is a selector to the Wire symbol in the tangent SDK import.
-func isTangentWire(pkg *packages.Package, expr ast.Expr, wantPath string) bool {
-	sel, ok := expr.(*ast.SelectorExpr)
-	if !ok {
-		return false
+	if len(out.diffs) > 0 {
+		sort.Strings(out.diffs)
+		for _, d := range out.diffs {
+			fmt.Fprint(w, d)
+		}
+		return fmt.Errorf("%d generated file(s) out of date; run gen", len(out.diffs))
 	}
-	if sel.Sel == nil || sel.Sel.Name != "Wire" {
-		return false
+	if !found {
+		return fmt.Errorf("no Wire[T] or Route[T] instantiations or tangent-tagged structs found in %s", strings.Join(patterns, " "))
 	}
-	id, ok := sel.X.(*ast.Ident)
-	if !ok {
-		return false
+	return nil
+}
+
+// generatedFiles returns the names of the files gen may write in a package
+// directory.
+func generatedFiles(outName string) []string {
+	return []string{decodeFileName, outName, externalFileName(outName)}
+}
+
+// firstLine returns the first line of the file at path, or "".
+func firstLine(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
 	}
-	obj := pkg.TypesInfo.Uses[id]
-	pkgName, ok := obj.(*types.PkgName)
-	if !ok {
-		return false
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return ""
 	}
-	return pkgName.Imported().Path() == wantPath
+	return strings.TrimSuffix(line, "\n")
 }
 
-// extractNamedStructType resolves the type of the provided type argument expression,
-// ensuring it is a named struct (after pointer/alias deref). Returns the type name and struct.
-func extractNamedStructType(pkg *packages.Package, typeExpr ast.Expr) (*types.Named, *types.Struct) {
-	tv, ok := pkg.TypesInfo.Types[typeExpr]
-	if !ok || tv.Type == nil {
-		return nil, nil
+// isGenerated reports whether the file at path starts with a "Code
+// generated ... DO NOT EDIT." line, as gen's own files and the easyjson
+// output earlier versions wrote do.
+func isGenerated(path string) bool {
+	line := firstLine(path)
+	return strings.HasPrefix(line, "// Code generated ") && strings.HasSuffix(line, " DO NOT EDIT.")
+}
+
+// isOwn reports whether gen may replace or remove the file at path: any
+// file carrying gen's header, which covers output written under another -o
+// name, and generated files under the names gen writes.
+func isOwn(path, outName string) bool {
+	if firstLine(path)+"\n\n" == header {
+		return true
 	}
-	t := deref(tv.Type)
-	if n, ok := t.(*types.Named); ok {
-		if st, ok := deref(n.Underlying()).(*types.Struct); ok {
-			return n, st
+	for _, name := range generatedFiles(outName) {
+		if filepath.Base(path) == name {
+			return isGenerated(path)
 		}
 	}
-	// Anonymous structs are not supported (cannot define methods)
-	return nil, nil
+	return false
 }
 
-func deref(t types.Type) types.Type {
-	for {
-		if p, ok := t.(*types.Pointer); ok {
-			t = p.Elem()
+// removeStale removes the files a previous run generated in pkg's
+// directory that this run did not write.
+func removeStale(pkg *packages.Package, out *emitter, outName string) error {
+	dir := filepath.Dir(pkg.GoFiles[0])
+	paths := append([]string(nil), pkg.GoFiles...)
+	for _, name := range generatedFiles(outName) {
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if seen[path] || out.written[path] || !isOwn(path, outName) {
 			continue
 		}
-		return t
+		seen[path] = true
+		if err := out.remove(path); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func hasEasyJSON(cg *ast.CommentGroup) bool {
-	if cg == nil {
-		return false
-	}
-	for _, c := range cg.List {
-		if strings.Contains(c.Text, "easyjson:json") {
-			return true
+// ownMarshallers returns the output types, by qualified name, that this run
+// gives marshallers in their own package. Their methods are stubs while
+// loading, if present at all, so other packages using them as output types
+// consult this instead of the method set.
+func ownMarshallers(pkgs []*packages.Package, stubs stubs) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, pkg := range pkgs {
+		outputs, err := findWireOutputTypes(pkg, stubs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pkg.PkgPath, err)
+		}
+		for n := range outputs {
+			if n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == pkg.PkgPath && n.TypeArgs().Len() == 0 {
+				out[n.Obj().Pkg().Path()+"."+n.Obj().Name()] = true
+			}
 		}
 	}
-	return false
+	return out, nil
 }
 
-func runEasyJSONTypesOnly(pkg *packages.Package, gens map[*types.Named]*types.Struct) error {
-	typeSpecByName := map[string]*ast.TypeSpec{}
-	fileByName := map[string]*ast.File{}
-	fset := pkg.Fset
-
-	for _, f := range pkg.Syntax {
-		for _, d := range f.Decls {
-			gd, ok := d.(*ast.GenDecl)
-			if !ok || gd.Tok != token.TYPE {
-				continue
-			}
-			for _, sp := range gd.Specs {
-				ts, ok := sp.(*ast.TypeSpec)
-				if !ok || ts.Name == nil {
-					continue
-				}
-				typeSpecByName[ts.Name.Name] = ts
-				fileByName[ts.Name.Name] = f
+// generatePackage emits the decoders and marshallers pkg needs, reporting
+// whether it needed any.
+func generatePackage(pkg *packages.Package, out *emitter, outName string, marshalled map[string]bool, stubs stubs) (bool, error) {
+	// Structs with `tangent:"..."` tags get reflection-free decoders.
+	decoders := findDecodeTargets(pkg, stubs)
+	if len(decoders) > 0 {
+		files, err := generateDecoders(pkg, decoders)
+		if err != nil {
+			return false, fmt.Errorf("decoder generate failed: %w", err)
+		}
+		for dst, b := range files {
+			if err := out.write(dst, b); err != nil {
+				return false, err
 			}
 		}
 	}
 
-	// ---- Partition targets into local vs external named types ----
-	localByDir := map[string][]string{} // dir -> []localTypeName
-	for n := range gens {
-		npkg := n.Obj().Pkg()
-		if npkg != nil && npkg.Path() == pkg.PkgPath {
-			// local
-			ts := typeSpecByName[n.Obj().Name()]
-			if ts == nil {
-				continue
-			}
-			filename := fset.Position(ts.Pos()).Filename
-			if filename == "" {
-				continue
-			}
-			dir := filepath.Dir(filename)
-			localByDir[dir] = append(localByDir[dir], n.Obj().Name())
-		}
+	// Discover the output type by locating a call to tangent_sdk.Wire[T](...)
+	toGenerate, err := findWireOutputTypes(pkg, stubs)
+	if err != nil {
+		return false, err
+	}
+	if len(toGenerate) == 0 {
+		return len(decoders) > 0, nil
 	}
 
-	// ---- Handle local types (lift type specs + imports, run easyjson) ----
-	for dir, roots := range localByDir {
-		pkgName := ""
-		if len(roots) > 0 {
-			if f := fileByName[roots[0]]; f != nil && f.Name != nil {
-				pkgName = f.Name.Name
-			}
+	if err := writeMarshallers(pkg, toGenerate, out, outName, marshalled, stubs); err != nil {
+		return false, fmt.Errorf("marshaller generate failed: %w", err)
+	}
+	return true, nil
+}
+
+// wireFuncs are the SDK functions whose type argument is an output type.
+var wireFuncs = map[string]bool{"Wire": true, "WireMulti": true, "WireBatchMulti": true, "Route": true, "RouteMulti": true}
+
+// findWireOutputTypes returns the type arguments of every instantiation of
+// github.com/telophasehq/tangent-sdk-go.Wire, WireMulti, WireBatchMulti,
+// Route or RouteMulti in the package, whether written explicitly
+// (Wire[T](...)) or inferred from the handler. Output types must be named
+// structs, or already implement easyjson.Marshaler; any other type argument
+// is an error.
+func findWireOutputTypes(pkg *packages.Package, stubs stubs) (map[*types.Named]*types.Struct, error) {
+	out := map[*types.Named]*types.Struct{}
+	var errs []string
+	for id, inst := range pkg.TypesInfo.Instances {
+		fn, ok := pkg.TypesInfo.Uses[id].(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != sdkImportPath || !wireFuncs[fn.Name()] {
+			continue
 		}
-		if pkgName == "" {
-			pkgName = pkg.Name
+		if inst.TypeArgs.Len() == 0 {
+			continue
 		}
-
-		// Collect transitive same-package named type dependencies
-		seen := map[string]bool{}
-		order := make([]*ast.TypeSpec, 0, len(roots)*2)
-		var addDeps func(name string)
-		addDeps = func(name string) {
-			if seen[name] {
-				return
-			}
-			seen[name] = true
-			ts := typeSpecByName[name]
-			if ts == nil {
-				return
-			}
-			ast.Inspect(ts.Type, func(n ast.Node) bool {
-				id, ok := n.(*ast.Ident)
-				if !ok || id.Name == "" {
-					return true
-				}
-				if obj, ok := pkg.TypesInfo.Uses[id]; ok {
-					if tn, ok := obj.(*types.TypeName); ok {
-						if tn.Pkg() != nil && tn.Pkg().Path() == pkg.PkgPath {
-							addDeps(tn.Name())
-						}
-					}
-				}
-				return true
-			})
-			order = append(order, ts)
+		t := inst.TypeArgs.At(0)
+		if name, st := namedStruct(t); st != nil {
+			out[name] = st
+			continue
 		}
-		for _, r := range roots {
-			addDeps(r)
+		if hasMarshalEasyJSON(t, stubs) {
+			continue
 		}
+		errs = append(errs, fmt.Sprintf("%s: %s[%s]: output type must be a named struct or implement easyjson.Marshaler",
+			pkg.Fset.Position(id.Pos()), fn.Name(), types.TypeString(t, types.RelativeTo(pkg.Types))))
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return out, nil
+}
 
-		// Build synthetic source: correct package + union of imports + the types
-		var src bytes.Buffer
-		fmt.Fprintf(&src, "package easyjson_local\n\n")
-
-		importSet := map[string]bool{}
-		var importSpecs []*ast.ImportSpec
-		seenFiles := map[*ast.File]bool{}
-		for _, ts := range order {
-			f := fileByName[ts.Name.Name]
-			if f == nil || seenFiles[f] {
-				continue
-			}
-			seenFiles[f] = true
-			for _, decl := range f.Decls {
-				gd, ok := decl.(*ast.GenDecl)
-				if !ok || gd.Tok != token.IMPORT {
-					continue
-				}
-				for _, s := range gd.Specs {
-					is := s.(*ast.ImportSpec)
-					key := ""
-					if is.Path != nil {
-						key = is.Path.Value
-					}
-					if !importSet[key] {
-						importSet[key] = true
-						importSpecs = append(importSpecs, is)
-					}
-				}
-			}
-		}
-		if len(importSpecs) > 0 {
-			src.WriteString("import (\n")
-			for _, is := range importSpecs {
-				var line bytes.Buffer
-				_ = printer.Fprint(&line, fset, is)
-				if !strings.HasSuffix(line.String(), "\n") {
-					line.WriteByte('\n')
-				}
-				src.WriteString("\t")
-				src.Write(line.Bytes())
-			}
-			src.WriteString(")\n\n")
+// hasMarshalEasyJSON reports whether t or *t has a MarshalEasyJSON method
+// other than a stub.
+func hasMarshalEasyJSON(t types.Type, stubs stubs) bool {
+	for _, t := range []types.Type{t, types.NewPointer(t)} {
+		obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "MarshalEasyJSON")
+		if fn, ok := obj.(*types.Func); ok && !stubs.declares(fn) {
+			return true
 		}
+	}
+	return false
+}
 
-		for _, ts := range order {
-			if !hasEasyJSON(ts.Doc) {
-				src.WriteString("//easyjson:json\n")
-			}
-			gd := &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{ts}}
-			if err := printer.Fprint(&src, fset, gd); err != nil {
-				return err
-			}
-			src.WriteByte('\n')
+// namedStruct returns t as a named struct type, dereferencing pointers.
+func namedStruct(t types.Type) (*types.Named, *types.Struct) {
+	t = deref(t)
+	if n, ok := t.(*types.Named); ok {
+		if st, ok := deref(n.Underlying()).(*types.Struct); ok {
+			return n, st
 		}
+	}
+	// Anonymous structs are not supported (cannot define methods)
+	return nil, nil
+}
 
-		tmp := "easyjson_local"
-		err := os.Mkdir(tmp, 0755)
-		if err != nil {
-			return err
+func deref(t types.Type) types.Type {
+	for {
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+			continue
 		}
-		defer os.RemoveAll(tmp)
+		return t
+	}
+}
 
-		formatted, err := imports.Process("types.go", src.Bytes(), &imports.Options{
-			Comments:  true,
-			TabWidth:  8,
-			TabIndent: true,
-		})
-		if err != nil {
-			log.Printf("warning: goimports failed (local): %v", err)
-			formatted = src.Bytes()
+// writeMarshallers writes the marshaller file for pkg's output types gens,
+// along with wrappers for those declared in other packages.
+func writeMarshallers(pkg *packages.Package, gens map[*types.Named]*types.Struct, out *emitter, outName string, marshalled map[string]bool, stubs stubs) error {
+	var local, external []*types.Named
+	var errs []string
+	for n := range gens {
+		obj := n.Obj()
+		pos := pkg.Fset.Position(obj.Pos())
+		if n.TypeArgs().Len() > 0 {
+			errs = append(errs, fmt.Sprintf("%s: output type %s: generic types are not supported", pos, n))
+			continue
 		}
-		if err := os.WriteFile(filepath.Join(tmp, "types.go"), formatted, 0o644); err != nil {
-			return err
+		if obj.Pkg() == nil || obj.Pkg().Path() != pkg.PkgPath {
+			switch {
+			case obj.Pkg() == nil:
+				errs = append(errs, fmt.Sprintf("%s: output type %s is predeclared", pos, n))
+			case !obj.Exported():
+				errs = append(errs, fmt.Sprintf("%s: output type %s is unexported", pos, n))
+			case marshalled[obj.Pkg().Path()+"."+obj.Name()] || hasMarshalEasyJSON(n, stubs):
+				// Marshallers were generated in its own package.
+			default:
+				external = append(external, n)
+			}
+			continue
 		}
-
-		cmd := exec.Command("go", "run", "github.com/mailru/easyjson/easyjson@latest", "-all", ".")
-		cmd.Dir = tmp
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("easyjson in %s: %w", dir, err)
+		if obj.Parent() != pkg.Types.Scope() {
+			errs = append(errs, fmt.Sprintf("%s: output type %s must be declared at package level", pos, obj.Name()))
+			continue
 		}
+		local = append(local, n)
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	if len(local) == 0 && len(external) == 0 {
+		return nil
+	}
 
-		ents, err := os.ReadDir(tmp)
-		if err != nil {
-			return err
-		}
-		var ezjson []os.DirEntry
-		for _, ent := range ents {
-			if ent.IsDir() || !strings.HasSuffix(ent.Name(), "_easyjson.go") {
-				continue
-			}
+	// External types get local wrapper types, generated alongside the
+	// package's own types and registered with the SDK at init.
+	sort.Slice(local, func(i, j int) bool { return local[i].Obj().Name() < local[j].Obj().Name() })
+	sort.Slice(external, func(i, j int) bool { return external[i].String() < external[j].String() })
+	wrappers := wrapperNames(pkg, external)
+	pkgDir := filepath.Dir(pkg.GoFiles[0])
+	if err := writeRegistrations(pkg, out, filepath.Join(pkgDir, externalFileName(outName)), external, wrappers); err != nil {
+		return err
+	}
+	b, err := generateMarshallers(pkg, local, external, wrappers, marshalled, stubs)
+	if err != nil {
+		return err
+	}
+	return out.write(filepath.Join(pkgDir, outName), b)
+}
 
-			ezjson = append(ezjson, ent)
-		}
+// externalFileName returns the name of the file registering marshallers for
+// output types declared in other packages, given the marshaller file's name:
+// json_external_generated.go for json_generated.go.
+func externalFileName(outName string) string {
+	if base, ok := strings.CutSuffix(outName, "_generated.go"); ok {
+		return base + "_external_generated.go"
+	}
+	return strings.TrimSuffix(outName, ".go") + "_external.go"
+}
 
-		if len(ezjson) != 1 {
-			fmt.Println("expected 1 file in easyjson_local. Found", ezjson)
+// wrapperNames picks a local type name for each external type, e.g.
+// tangentgenOcsfEvent for ocsf.Event. A previous run's generated files are
+// emptied while loading, so their names don't count as taken and reruns
+// pick the same names.
+func wrapperNames(pkg *packages.Package, external []*types.Named) []string {
+	taken := func(name string) bool {
+		return pkg.Types.Scope().Lookup(name) != nil
+	}
+	names := make([]string, len(external))
+	used := map[string]bool{}
+	for i, n := range external {
+		pkgName := n.Obj().Pkg().Name()
+		base := "tangentgen" + strings.ToUpper(pkgName[:1]) + pkgName[1:] + n.Obj().Name()
+		name := base
+		for k := 2; used[name] || taken(name); k++ {
+			name = fmt.Sprintf("%s%d", base, k)
 		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
 
-		e := ezjson[0]
-		b, err := os.ReadFile(filepath.Join(tmp, e.Name()))
-		if err != nil {
-			return err
-		}
-		b = pkgLineRx.ReplaceAllFunc(b, func(line []byte) []byte {
-			if string(bytes.TrimSpace(line)) == "package easyjson_local" {
-				return []byte("package main")
-			}
-			return line
-		})
-		// Package header already correct; no rewrites to "main".
-		dst := filepath.Join(dir, "json_generated.go")
-		if err := os.WriteFile(dst, b, 0o644); err != nil {
-			return err
-		}
+// wrapperImport is the import name for the i'th external type's package in
+// generated files.
+func wrapperImport(i int) string {
+	return fmt.Sprintf("tangentgen_ext%d", i)
+}
+
+// writeRegistrations writes dst, registering each wrapper's marshaller for
+// its external type. It writes nothing if there are none.
+func writeRegistrations(pkg *packages.Package, out *emitter, dst string, external []*types.Named, wrappers []string) error {
+	if len(external) == 0 {
+		return nil
 	}
 
-	return nil
+	var src bytes.Buffer
+	src.WriteString(header)
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", pkg.Name)
+	fmt.Fprintf(&src, "\ttangent_sdk %q\n", sdkImportPath)
+	fmt.Fprintf(&src, "\tjwriter %q\n", "github.com/mailru/easyjson/jwriter")
+	for i, n := range external {
+		fmt.Fprintf(&src, "\t%s %q\n", wrapperImport(i), n.Obj().Pkg().Path())
+	}
+	src.WriteString(")\n\n")
+	src.WriteString("// Output types declared in other packages can't be given methods, so they\n")
+	src.WriteString("// are marshalled through local wrapper types.\n")
+	src.WriteString("type (\n")
+	for i, n := range external {
+		fmt.Fprintf(&src, "\t%s %s.%s\n", wrappers[i], wrapperImport(i), n.Obj().Name())
+	}
+	src.WriteString(")\n\n")
+	src.WriteString("func init() {\n")
+	for i, n := range external {
+		fmt.Fprintf(&src, "\ttangent_sdk.RegisterEasyJSON(func(w *jwriter.Writer, v %s.%s) { %s(v).MarshalEasyJSON(w) })\n",
+			wrapperImport(i), n.Obj().Name(), wrappers[i])
+	}
+	src.WriteString("}\n")
+
+	b, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	return out.write(dst, b)
 }
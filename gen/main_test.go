@@ -0,0 +1,365 @@
+package main
+
+import (
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestRemoveStale(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	decode := write(decodeFileName, header+"package p\n")
+	marshal := write("json_generated.go", "// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.\n\npackage p\n")
+	own := write("json_external_generated.go", "package p\n") // not generated: kept
+	write("p.go", "package p\n")
+
+	for _, path := range []string{decode, marshal} {
+		if !isGenerated(path) {
+			t.Errorf("isGenerated(%s) = false", filepath.Base(path))
+		}
+	}
+	if isGenerated(own) {
+		t.Errorf("isGenerated(%s) = true", filepath.Base(own))
+	}
+
+	pkg := []*packages.Package{{Name: "p", GoFiles: []string{decode, marshal, own, filepath.Join(dir, "p.go")}}}
+	overlay := stubGenerated(pkg, "json_generated.go")
+	if len(overlay) != 2 || string(overlay[decode]) != header+"package p\n" {
+		t.Errorf("stubGenerated = %q", overlay)
+	}
+
+	check := &emitter{check: true, written: map[string]bool{}}
+	check.write(marshal, []byte("new"))
+	if err := removeStale(pkg[0], check, "json_generated.go"); err != nil {
+		t.Fatal(err)
+	}
+	if len(check.diffs) != 2 || !strings.Contains(check.diffs[1], "--- "+displayPath(decode)) {
+		t.Errorf("check diffs = %q", check.diffs)
+	}
+	if _, err := os.Stat(decode); err != nil {
+		t.Errorf("-check removed %s", decodeFileName)
+	}
+
+	out := &emitter{written: map[string]bool{}}
+	out.written[marshal] = true
+	if err := removeStale(pkg[0], out, "json_generated.go"); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{decode: false, marshal: true, own: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s exists: %t, want %t", filepath.Base(path), err == nil, want)
+		}
+	}
+}
+
+// tempModule writes files, keyed by slash-separated path, into a new module
+// example.com/m that uses this checkout of the SDK, and returns its
+// directory. Builds in it stay offline: it requires the SDK's own
+// dependency versions, which the module cache already holds.
+func tempModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a module")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomod, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gosum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mod strings.Builder
+	for _, line := range strings.Split(string(gomod), "\n") {
+		switch {
+		case strings.HasPrefix(line, "module "):
+			line = "module example.com/m"
+		case strings.HasPrefix(line, "tool "):
+			continue
+		}
+		mod.WriteString(line + "\n")
+	}
+	mod.WriteString("require " + sdkImportPath + " v0.0.0\n\nreplace " + sdkImportPath + " => " + root + "\n")
+
+	dir := t.TempDir()
+	files["go.mod"] = mod.String()
+	files["go.sum"] = string(gosum)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "off")
+	return dir
+}
+
+// goCmd runs the go command in dir, failing t with its output on error.
+func goCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// pluginModule is a module with a plugin and a shared library package, each
+// with an output type.
+var pluginModule = map[string]string{
+	"main.go": `package main
+
+import (
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+	"example.com/m/lib"
+)
+
+type Out struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+func init() {
+	tangent_sdk.WireMulti(tangent_sdk.Metadata{Name: "m"}, nil, func(tangent_sdk.Log) ([]Out, error) { return nil, nil })
+	tangent_sdk.WireMulti(tangent_sdk.Metadata{Name: "lib"}, nil, func(tangent_sdk.Log) ([]lib.Out, error) { return nil, nil })
+}
+
+func main() {}
+`,
+	"lib/lib.go": `package lib
+
+type Out struct {
+	Host string ` + "`json:\"host\"`" + `
+}
+`,
+	"lib/wire.go": `package lib
+
+import sdk "github.com/telophasehq/tangent-sdk-go"
+
+var Binding = sdk.RouteMulti(sdk.Selector{}, func(sdk.Log) ([]Out, error) { return nil, nil })
+`,
+}
+
+// readGenerated returns the generated files below dir, keyed by
+// slash-separated path, each mapped to its package clause.
+func readGenerated(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	got := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || !isGenerated(path) {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "package ") {
+				got[filepath.ToSlash(rel)] = line
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestRunPatterns(t *testing.T) {
+	dir := tempModule(t, maps.Clone(pluginModule))
+
+	// A pattern naming one package leaves the others alone.
+	if err := run(dir, []string{"./lib"}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"lib/json_generated.go": "package lib"}
+	if got := readGenerated(t, dir); !maps.Equal(got, want) {
+		t.Errorf("./lib generated %v, want %v", got, want)
+	}
+
+	// ./... covers both. main uses lib.Out, which lib marshals itself, so
+	// it needs no wrapper of its own.
+	if err := run(dir, []string{"./..."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want["json_generated.go"] = "package main"
+	if got := readGenerated(t, dir); !maps.Equal(got, want) {
+		t.Errorf("./... generated %v, want %v", got, want)
+	}
+	goCmd(t, dir, "vet", "./...")
+
+	if err := run(dir, []string{"./nothing/..."}, "json_generated.go", false, io.Discard); err == nil {
+		t.Error("a pattern matching no packages succeeded")
+	}
+}
+
+func TestRunOutName(t *testing.T) {
+	files := maps.Clone(pluginModule)
+	files["ext/ext.go"] = "package ext\n\ntype Event struct{ ID int }\n"
+	files["ext.go"] = `package main
+
+import (
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+	"example.com/m/ext"
+)
+
+func init() {
+	tangent_sdk.WireMulti(tangent_sdk.Metadata{Name: "ext"}, nil, func(tangent_sdk.Log) ([]ext.Event, error) { return nil, nil })
+}
+`
+	dir := tempModule(t, files)
+
+	if err := run(dir, []string{"./..."}, "marshal.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"marshal.go":          "package main",
+		"marshal_external.go": "package main",
+		"lib/marshal.go":      "package lib",
+	}
+	if got := readGenerated(t, dir); !maps.Equal(got, want) {
+		t.Errorf("-o marshal.go generated %v, want %v", got, want)
+	}
+	goCmd(t, dir, "vet", "./...")
+
+	// Switching names replaces the old files.
+	if err := run(dir, []string{"./..."}, "z_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	got := readGenerated(t, dir)
+	if _, ok := got["z_external_generated.go"]; !ok || len(got) != 3 {
+		t.Errorf("-o z_generated.go left %v", got)
+	}
+
+	for _, bad := range []string{"sub/x.go", "x.txt", ""} {
+		if err := run(dir, []string{"./..."}, bad, false, io.Discard); err == nil || !strings.HasPrefix(err.Error(), "-o ") {
+			t.Errorf("-o %q: err = %v", bad, err)
+		}
+	}
+}
+
+func TestRunKeepsGeneratedMethods(t *testing.T) {
+	files := map[string]string{
+		"main.go": `package main
+
+import tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+type In struct {
+	Host string ` + "`tangent:\"host\"`" + `
+}
+
+type Out struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+func init() {
+	tangent_sdk.WireMulti(tangent_sdk.Metadata{Name: "m"}, nil, func(tangent_sdk.Log) ([]Out, error) { return nil, nil })
+}
+
+func main() {}
+`,
+	}
+	dir := tempModule(t, files)
+	if err := run(dir, []string{"."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	// Code calling the generated methods must not stop them being
+	// regenerated.
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("use.go", `package main
+
+import tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+
+func use(l tangent_sdk.Log) ([]byte, error) {
+	var in In
+	if err := in.DecodeFromLog(l); err != nil {
+		return nil, err
+	}
+	return Out{Name: in.Host}.MarshalJSON()
+}
+`)
+	if err := run(dir, []string{"."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	goCmd(t, dir, "vet", ".")
+
+	// A stale tree fails -check with a diff and is left as it was.
+	before := readFiles(t, dir)
+	write("main.go", strings.Replace(files["main.go"], "type Out struct {\n", "type Out struct {\n\tKind string `json:\"kind\"`\n", 1))
+	before["main.go"] = readFiles(t, dir)["main.go"]
+	var diff strings.Builder
+	if err := run(dir, []string{"."}, "json_generated.go", true, &diff); err == nil {
+		t.Error("-check on a stale tree succeeded")
+	}
+	if !strings.Contains(diff.String(), "json_generated.go (generated)") || !strings.Contains(diff.String(), "+\t\tout.String(string(in.Kind))") {
+		t.Errorf("-check diff:\n%s", diff.String())
+	}
+	if after := readFiles(t, dir); !maps.Equal(after, before) {
+		t.Error("-check changed the tree")
+	}
+
+	// A hand-written method takes over from the generated one.
+	write("main.go", files["main.go"])
+	write("json.go", "package main\n\nfunc (Out) MarshalJSON() ([]byte, error) { return []byte(`{}`), nil }\n")
+	if err := run(dir, []string{"."}, "json_generated.go", false, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	goCmd(t, dir, "vet", ".")
+	if b := readFiles(t, dir)["json_generated.go"]; strings.Contains(b, ") MarshalJSON(") {
+		t.Errorf("json_generated.go still defines MarshalJSON:\n%s", b)
+	}
+}
+
+// readFiles returns the contents of the files in dir, by name.
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[e.Name()] = string(b)
+	}
+	return files
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// stubGenerated returns a packages.Config overlay that replaces every file
+// gen wrote in pkgs with a stub. A stub keeps only the methods on types the
+// package still declares itself, with bodies that panic, so that code
+// calling generated methods type-checks while nothing else of the old
+// output can break loading or feed back into what is generated.
+func stubGenerated(pkgs []*packages.Package, outName string) map[string][]byte {
+	overlay := map[string][]byte{}
+	for _, pkg := range pkgs {
+		var own, user []string
+		for _, path := range pkg.GoFiles {
+			if isOwn(path, outName) {
+				own = append(own, path)
+			} else {
+				user = append(user, path)
+			}
+		}
+		if len(own) == 0 {
+			continue
+		}
+		declared, methods := userDecls(user)
+		for _, path := range own {
+			overlay[path] = stubFile(path, pkg.Name, declared, methods)
+		}
+	}
+	return overlay
+}
+
+// userDecls returns the types declared in files that can carry methods and
+// the methods declared on them, as "T.M".
+func userDecls(files []string) (map[string]bool, map[string]bool) {
+	declared, methods := map[string]bool{}, map[string]bool{}
+	fset := token.NewFileSet()
+	for _, path := range files {
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || ts.Assign.IsValid() || ts.TypeParams != nil {
+						continue
+					}
+					switch ts.Type.(type) {
+					case *ast.InterfaceType, *ast.StarExpr:
+						continue
+					}
+					declared[ts.Name.Name] = true
+				}
+			case *ast.FuncDecl:
+				if name := recvName(decl); name != "" {
+					methods[name+"."+decl.Name.Name] = true
+				}
+			}
+		}
+	}
+	return declared, methods
+}
+
+// recvName returns the name of the type decl is a method of, or "" if decl
+// is a function or a method of a generic type.
+func recvName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) != 1 {
+		return ""
+	}
+	t := decl.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// stubFile returns the stub for the generated file at filename: the header,
+// the package clause, and its methods on declared types that do not already
+// have a method of the same name, with the imports their signatures use.
+func stubFile(filename, pkgName string, declared, methods map[string]bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(header + "package " + pkgName + "\n")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return buf.Bytes()
+	}
+
+	var funcs bytes.Buffer
+	used := map[string]bool{}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := recvName(fn)
+		if !declared[name] || methods[name+"."+fn.Name.Name] {
+			continue
+		}
+		ast.Inspect(fn.Type, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+		stub := &ast.FuncDecl{Recv: fn.Recv, Name: fn.Name, Type: fn.Type}
+		funcs.WriteString("\n")
+		if err := printer.Fprint(&funcs, fset, stub); err != nil {
+			return buf.Bytes()
+		}
+		funcs.WriteString(" { panic(\"stub\") }\n")
+	}
+	if funcs.Len() == 0 {
+		return buf.Bytes()
+	}
+
+	var imports []string
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if used[name] {
+			imports = append(imports, name+" "+strconv.Quote(p))
+		}
+	}
+	if len(imports) > 0 {
+		sort.Strings(imports)
+		buf.WriteString("\nimport (\n")
+		for _, imp := range imports {
+			buf.WriteString("\t" + imp + "\n")
+		}
+		buf.WriteString(")\n")
+	}
+	buf.Write(funcs.Bytes())
+	return buf.Bytes()
+}
+
+// stubs identifies the stub methods stubGenerated declared, which stand in
+// for methods this run regenerates or drops and so are not counted as the
+// package's own.
+type stubs struct {
+	fset    *token.FileSet
+	overlay map[string][]byte
+}
+
+// declares reports whether obj was declared in a stub.
+func (s stubs) declares(obj types.Object) bool {
+	_, ok := s.overlay[s.fset.Position(obj.Pos()).Filename]
+	return ok
+}
@@ -0,0 +1,271 @@
+package tangent_sdk
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+)
+
+// Scalar is the set of Go types Get can return.
+type Scalar interface {
+	~string | ~bool |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// GetOption configures Get and GetDefault.
+type GetOption func(*getOptions)
+
+type getOptions struct {
+	coerce bool
+	def    any // a T from WithDefault, or nil
+}
+
+// Coerce lets Get convert between scalar kinds: numbers to and from strings
+// ("443" to 443 and back), integral floats to integers (443.0 to 443), and
+// booleans from strings accepted by strconv.ParseBool ("true", "0", "F") or
+// from the numbers 0 and 1.
+func Coerce() GetOption {
+	return func(o *getOptions) { o.coerce = true }
+}
+
+// WithDefault makes Get return v rather than T's zero value when the log
+// holds no usable value. ok is still false in that case. v must be of Get's
+// type T exactly: Get[float64] needs WithDefault(5.0), not WithDefault(5),
+// and panics otherwise.
+func WithDefault[T Scalar](v T) GetOption {
+	return func(o *getOptions) { o.def = v }
+}
+
+// Get returns the scalar at path as a T:
+//
+//	port, _ := tangent_sdk.Get[int](l, "dst_port", tangent_sdk.Coerce())
+//
+// Without Coerce the scalar must already be of T's kind, as with GetString
+// and friends, except that ints widen to floats. ok reports whether the log
+// held a usable value; when it is false Get returns T's zero value. Integers
+// that overflow T are not usable.
+func Get[T Scalar](l Log, path string, opts ...GetOption) (T, bool) {
+	var out T
+	var o getOptions
+	if len(opts) > 0 {
+		o = applyGetOptions(opts)
+	}
+	val, ok := l.Get(path)
+	if !ok {
+		return fallback[T](o.def), false
+	}
+	if o.coerce {
+		if val, ok = coerce(val, kindOf(out)); !ok {
+			return fallback[T](o.def), false
+		}
+	}
+
+	switch p := any(&out).(type) {
+	case *string:
+		ok = setString(p, val)
+	case *bool:
+		ok = setBool(p, val)
+	case *int:
+		ok = setInt(p, val)
+	case *int8:
+		ok = setInt(p, val)
+	case *int16:
+		ok = setInt(p, val)
+	case *int32:
+		ok = setInt(p, val)
+	case *int64:
+		ok = setInt(p, val)
+	case *uint:
+		ok = setUint(p, val)
+	case *uint8:
+		ok = setUint(p, val)
+	case *uint16:
+		ok = setUint(p, val)
+	case *uint32:
+		ok = setUint(p, val)
+	case *uint64:
+		ok = setUint(p, val)
+	case *float32:
+		ok = setFloat(p, val)
+	case *float64:
+		ok = setFloat(p, val)
+	default:
+		// Defined types such as `type Port uint16` go through reflect.
+		var v T
+		ok = setScalar(reflect.ValueOf(&v).Elem(), val) == nil
+		out = v
+	}
+	if !ok {
+		return fallback[T](o.def), false
+	}
+	return out, true
+}
+
+// applyGetOptions is kept out of Get so that calls without options don't
+// move Get's options to the heap.
+func applyGetOptions(opts []GetOption) getOptions {
+	var o getOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// fallback returns the default set by WithDefault, or T's zero value.
+func fallback[T Scalar](def any) T {
+	var zero T
+	if def == nil {
+		return zero
+	}
+	v, ok := def.(T)
+	if !ok {
+		panic(fmt.Sprintf("tangent_sdk: WithDefault(%T) passed to Get[%T]", def, zero))
+	}
+	return v
+}
+
+// GetDefault is like Get with WithDefault(def), for callers that don't need
+// ok. Go infers T from def:
+//
+//	sev := tangent_sdk.GetDefault(l, "severity", 5.0, tangent_sdk.Coerce())
+func GetDefault[T Scalar](l Log, path string, def T, opts ...GetOption) T {
+	if v, ok := Get[T](l, path, opts...); ok {
+		return v
+	}
+	return def
+}
+
+// kindOf returns the reflect.Kind of v's type, without reflect for the
+// predeclared types.
+func kindOf[T Scalar](v T) reflect.Kind {
+	switch any(v).(type) {
+	case string:
+		return reflect.String
+	case bool:
+		return reflect.Bool
+	case int, int8, int16, int32, int64:
+		return reflect.Int
+	case uint, uint8, uint16, uint32, uint64:
+		return reflect.Uint
+	case float32, float64:
+		return reflect.Float64
+	}
+	return reflect.TypeOf(v).Kind()
+}
+
+func setString(p *string, val Value) bool {
+	s, ok := val.AsString()
+	if ok {
+		*p = s
+	}
+	return ok
+}
+
+func setBool(p *bool, val Value) bool {
+	b, ok := val.AsBool()
+	if ok {
+		*p = b
+	}
+	return ok
+}
+
+func setInt[I int | int8 | int16 | int32 | int64](p *I, val Value) bool {
+	i, ok := val.AsInt()
+	if !ok || int64(I(i)) != i {
+		return false
+	}
+	*p = I(i)
+	return true
+}
+
+func setUint[U uint | uint8 | uint16 | uint32 | uint64](p *U, val Value) bool {
+	i, ok := val.AsInt()
+	if !ok || i < 0 || uint64(U(i)) != uint64(i) {
+		return false
+	}
+	*p = U(i)
+	return true
+}
+
+func setFloat[F float32 | float64](p *F, val Value) bool {
+	if f, ok := val.AsFloat(); ok {
+		*p = F(f)
+		return true
+	}
+	if i, ok := val.AsInt(); ok {
+		*p = F(i)
+		return true
+	}
+	return false
+}
+
+// coerce converts v to the scalar kind setScalar expects for a Go value of
+// kind k.
+func coerce(v Value, k reflect.Kind) (Value, bool) {
+	switch k {
+	case reflect.String:
+		switch v.Kind() {
+		case KindInt:
+			i, _ := v.AsInt()
+			return valueOf(log.ScalarStr(strconv.FormatInt(i, 10))), true
+		case KindFloat:
+			f, _ := v.AsFloat()
+			return valueOf(log.ScalarStr(formatFloat(f))), true
+		case KindBool:
+			b, _ := v.AsBool()
+			return valueOf(log.ScalarStr(strconv.FormatBool(b))), true
+		case KindBytes:
+			b, _ := v.AsBytes()
+			return valueOf(log.ScalarStr(string(b))), true
+		}
+	case reflect.Bool:
+		switch v.Kind() {
+		case KindString:
+			s, _ := v.AsString()
+			b, err := strconv.ParseBool(strings.TrimSpace(s))
+			return valueOf(log.ScalarBoolean(b)), err == nil
+		case KindInt:
+			i, _ := v.AsInt()
+			return valueOf(log.ScalarBoolean(i == 1)), i == 0 || i == 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch v.Kind() {
+		case KindFloat:
+			f, _ := v.AsFloat()
+			return intFromFloat(f)
+		case KindString:
+			s, _ := v.AsString()
+			s = strings.TrimSpace(s)
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return valueOf(log.ScalarInt(i)), true
+			}
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return intFromFloat(f)
+			}
+			return v, false
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Kind() == KindString {
+			s, _ := v.AsString()
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			return valueOf(log.ScalarFloat(f)), err == nil
+		}
+	}
+	return v, true
+}
+
+// intFromFloat converts f to an int scalar if it is integral and fits in an
+// int64.
+func intFromFloat(f float64) (Value, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return Value{}, false
+	}
+	return valueOf(log.ScalarInt(int64(f))), true
+}
@@ -0,0 +1,97 @@
+package tangent_sdk
+
+import "testing"
+
+type testPort uint16
+
+func TestGet(t *testing.T) {
+	l := testLogs(t, `{"port":"443","n":443,"f":443.0,"frac":1.5,"big":70000,"neg":-1,"ok":"true","s":"x"}`)[0]
+
+	check := func(name string, got, want any, ok, wantOK bool) {
+		t.Helper()
+		if got != want || ok != wantOK {
+			t.Errorf("%s = %v, %v; want %v, %v", name, got, ok, want, wantOK)
+		}
+	}
+	v1, ok := Get[int64](l, "n")
+	check("int64 n", v1, int64(443), ok, true)
+	v2, ok := Get[int64](l, "port")
+	check("int64 port", v2, int64(0), ok, false)
+	v3, ok := Get[int64](l, "port", Coerce())
+	check("int64 port coerced", v3, int64(443), ok, true)
+	v4, ok := Get[int](l, "f", Coerce())
+	check("int f coerced", v4, 443, ok, true)
+	v5, ok := Get[int](l, "frac", Coerce())
+	check("int frac coerced", v5, 0, ok, false)
+	v6, ok := Get[uint16](l, "big")
+	check("uint16 big", v6, uint16(0), ok, false)
+	v7, ok := Get[uint](l, "neg")
+	check("uint neg", v7, uint(0), ok, false)
+	v8, ok := Get[float64](l, "n")
+	check("float64 n", v8, 443.0, ok, true)
+	v9, ok := Get[string](l, "n", Coerce())
+	check("string n coerced", v9, "443", ok, true)
+	v10, ok := Get[bool](l, "ok", Coerce())
+	check("bool ok coerced", v10, true, ok, true)
+	v11, ok := Get[testPort](l, "port", Coerce())
+	check("testPort port coerced", v11, testPort(443), ok, true)
+	v12, ok := Get[testPort](l, "big")
+	check("testPort big", v12, testPort(0), ok, false)
+
+	if got := GetDefault(l, "missing", "443"); got != "443" {
+		t.Errorf("GetDefault string = %q", got)
+	}
+	if got := GetDefault(l, "s", 7); got != 7 {
+		t.Errorf("GetDefault on a string for int = %d", got)
+	}
+	if got := GetDefault(l, "n", 7); got != 443 {
+		t.Errorf("GetDefault present = %d", got)
+	}
+}
+
+func TestGetWithDefault(t *testing.T) {
+	l := testLogs(t, `{"port":"443","n":443,"s":"x"}`)[0]
+
+	tests := []struct {
+		name   string
+		got    func() (any, bool)
+		want   any
+		wantOK bool
+	}{
+		{"missing", func() (any, bool) { return Get[int](l, "missing", WithDefault(7)) }, 7, false},
+		{"wrong kind", func() (any, bool) { return Get[int](l, "port", WithDefault(7)) }, 7, false},
+		{"coerced", func() (any, bool) { return Get[int](l, "port", Coerce(), WithDefault(7)) }, 443, true},
+		{"coerce fails", func() (any, bool) { return Get[int](l, "s", Coerce(), WithDefault(7)) }, 7, false},
+		{"present", func() (any, bool) { return Get[int](l, "n", WithDefault(7)) }, 443, true},
+		{"overflow", func() (any, bool) { return Get[int8](l, "n", WithDefault[int8](-1)) }, int8(-1), false},
+		{"defined type", func() (any, bool) { return Get[testPort](l, "s", WithDefault(testPort(80))) }, testPort(80), false},
+		{"string", func() (any, bool) { return Get[string](l, "n", WithDefault("none")) }, "none", false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.got()
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: Get = %v (%T), %t; want %v (%T), %t", tt.name, got, got, ok, tt.want, tt.want, tt.wantOK)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != "tangent_sdk: WithDefault(int) passed to Get[float64]" {
+			t.Errorf("mismatched default: recovered %v", r)
+		}
+	}()
+	Get[float64](l, "missing", WithDefault(5))
+}
+
+func TestGetAllocs(t *testing.T) {
+	l := testLogs(t, `{"n":443}`)[0]
+	// Only the in-process lookup itself may allocate.
+	base := testing.AllocsPerRun(100, func() {
+		l.Get("n")
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		Get[int64](l, "n")
+	})
+	if allocs > base {
+		t.Errorf("Get[int64] allocates %v times per call, Log.Get %v", allocs, base)
+	}
+}
@@ -6,6 +6,7 @@ toolchain go1.24.10
 
 require (
 	github.com/mailru/easyjson v0.9.1
+	github.com/tetratelabs/wazero v1.9.0
 	go.bytecodealliance.org/cm v0.3.0
 	golang.org/x/tools v0.38.0
 )
@@ -18,7 +19,6 @@ require (
 	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/regclient/regclient v0.8.3 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
-	github.com/tetratelabs/wazero v1.9.0 // indirect
 	github.com/ulikunitz/xz v0.5.12 // indirect
 	github.com/urfave/cli/v3 v3.3.3 // indirect
 	go.bytecodealliance.org v0.7.0 // indirect
@@ -94,3 +94,30 @@ func GetInt64List(v log.Logview, path string) ([]int64, bool) {
 	}
 	return out, true
 }
+
+// GetMap returns the scalar members of the object at path as string, int64,
+// float64, bool or []byte values.
+func GetMap(v log.Logview, path string) (map[string]any, bool) {
+	opt := v.GetMap(path)
+	if opt.None() {
+		return nil, false
+	}
+	lst := opt.Value()
+	out := make(map[string]any, lst.Len())
+	for _, kv := range lst.Slice() {
+		s := kv.F1
+		switch {
+		case s.Str() != nil:
+			out[kv.F0] = *s.Str()
+		case s.Int() != nil:
+			out[kv.F0] = *s.Int()
+		case s.Float() != nil:
+			out[kv.F0] = *s.Float()
+		case s.Boolean() != nil:
+			out[kv.F0] = *s.Boolean()
+		case s.Bytes() != nil:
+			out[kv.F0] = append([]byte(nil), s.Bytes().Slice()...)
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,17 @@
+//go:build !wasm
+
+package tangent_sdk
+
+import (
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+
+	"go.bytecodealliance.org/cm"
+)
+
+// hostLogs is only reachable from a Tangent host, which loads wasm builds.
+// Referencing the Logview methods here would leave native binaries with
+// undefined host imports, so packages calling Wire could not link under
+// go test.
+func hostLogs(cm.List[log.Logview]) ([]Log, func()) {
+	panic("tangent: host log views are only available in wasm builds")
+}
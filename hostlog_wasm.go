@@ -0,0 +1,24 @@
+//go:build wasm
+
+package tangent_sdk
+
+import (
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+
+	"go.bytecodealliance.org/cm"
+)
+
+// hostLogs wraps the host's log views for one process-logs call. The
+// returned func drops them once the batch has been encoded.
+func hostLogs(input cm.List[log.Logview]) ([]Log, func()) {
+	items := append([]log.Logview(nil), input.Slice()...)
+	logs := make([]Log, len(items))
+	for i, lv := range items {
+		logs[i] = Log{logview: lv}
+	}
+	return logs, func() {
+		for _, lv := range items {
+			lv.ResourceDrop()
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package jsonlog implements logsource.Source over an in-memory JSON document.
+// Paths are read with logpath, and numbers written as integers that fit in
+// an int64 become ints, all others floats. These are the SDK's own rules for
+// running plugins without a host; they are not checked against the host's
+// logview, which may disagree on edge cases.
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/logpath"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"go.bytecodealliance.org/cm"
+)
+
+// View is a parsed JSON document that answers Logview calls.
+type View struct {
+	raw string
+	doc any
+}
+
+// Parse decodes a single JSON document.
+func Parse(raw []byte) (*View, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("jsonlog: trailing data after JSON document")
+	}
+	return &View{raw: string(bytes.TrimSpace(raw)), doc: doc}, nil
+}
+
+// FromValue builds a View from an already decoded value such as a
+// map[string]any. The value is round-tripped through encoding/json so numbers
+// and nested types are normalized exactly as if they had been parsed.
+func FromValue(v any) (*View, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(raw)
+}
+
+// Doc returns the decoded document. Numbers are json.Number values.
+func (v *View) Doc() any {
+	return v.doc
+}
+
+// Lookup resolves path against the document.
+func (v *View) Lookup(path string) (any, bool) {
+	segs, err := logpath.Parse(path)
+	if err != nil {
+		return nil, false
+	}
+	return Walk(v.doc, segs)
+}
+
+// Walk follows segs from node.
+func Walk(node any, segs []logpath.Segment) (any, bool) {
+	for _, s := range segs {
+		switch n := node.(type) {
+		case map[string]any:
+			if s.IsIndex {
+				return nil, false
+			}
+			child, ok := n[s.Key]
+			if !ok {
+				return nil, false
+			}
+			node = child
+		case []any:
+			if !s.IsIndex || s.Index >= len(n) {
+				return nil, false
+			}
+			node = n[s.Index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// ToScalar converts a decoded JSON value to a host scalar. Objects, arrays and
+// null have no scalar form. Integral numbers that fit in an int64 become ints,
+// everything else becomes a float.
+func ToScalar(node any) (log.Scalar, bool) {
+	switch n := node.(type) {
+	case string:
+		return log.ScalarStr(n), true
+	case bool:
+		return log.ScalarBoolean(n), true
+	case json.Number:
+		if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+			return log.ScalarInt(i), true
+		}
+		if f, err := n.Float64(); err == nil {
+			return log.ScalarFloat(f), true
+		}
+	case float64:
+		return log.ScalarFloat(n), true
+	}
+	return log.Scalar{}, false
+}
+
+// SortedKeys returns the keys of m sorted, the order Keys reports them in.
+func SortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *View) Get(path string) cm.Option[log.Scalar] {
+	node, ok := v.Lookup(path)
+	if !ok {
+		return cm.None[log.Scalar]()
+	}
+	s, ok := ToScalar(node)
+	if !ok {
+		return cm.None[log.Scalar]()
+	}
+	return cm.Some(s)
+}
+
+func (v *View) GetList(path string) cm.Option[cm.List[log.Scalar]] {
+	node, _ := v.Lookup(path)
+	arr, ok := node.([]any)
+	if !ok {
+		return cm.None[cm.List[log.Scalar]]()
+	}
+	out := make([]log.Scalar, 0, len(arr))
+	for _, elem := range arr {
+		if s, ok := ToScalar(elem); ok {
+			out = append(out, s)
+		}
+	}
+	return cm.Some(cm.ToList(out))
+}
+
+func (v *View) GetMap(path string) cm.Option[cm.List[cm.Tuple[string, log.Scalar]]] {
+	node, _ := v.Lookup(path)
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return cm.None[cm.List[cm.Tuple[string, log.Scalar]]]()
+	}
+	out := make([]cm.Tuple[string, log.Scalar], 0, len(obj))
+	for _, k := range SortedKeys(obj) {
+		if s, ok := ToScalar(obj[k]); ok {
+			out = append(out, cm.Tuple[string, log.Scalar]{F0: k, F1: s})
+		}
+	}
+	return cm.Some(cm.ToList(out))
+}
+
+func (v *View) Has(path string) bool {
+	_, ok := v.Lookup(path)
+	return ok
+}
+
+func (v *View) Keys(path string) cm.List[string] {
+	node, _ := v.Lookup(path)
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return cm.ToList([]string{})
+	}
+	return cm.ToList(SortedKeys(obj))
+}
+
+func (v *View) Len(path string) cm.Option[uint32] {
+	node, _ := v.Lookup(path)
+	switch n := node.(type) {
+	case []any:
+		return cm.Some(uint32(len(n)))
+	case map[string]any:
+		return cm.Some(uint32(len(n)))
+	}
+	return cm.None[uint32]()
+}
+
+func (v *View) Log() string {
+	return v.raw
+}
+
+// ResourceDrop is a no-op; the document is garbage collected.
+func (v *View) ResourceDrop() {}
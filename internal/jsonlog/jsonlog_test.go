@@ -0,0 +1,229 @@
+package jsonlog
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+)
+
+const doc = `{
+	"s": "str", "i": 42, "neg": -7, "f": 1.5, "whole": 2.0, "exp": 1e3, "big": 18446744073709551616,
+	"t": true, "n": null,
+	"obj": {"z": 1, "a": "x", "m": {"k": 1}, "l": [1], "null": null},
+	"empty": {}, "none": [],
+	"list": [1, "two", 3.5, false, null, {"k": "v"}, [9]],
+	"nested": [[1, 2], [3]],
+	"k.dot": {"v": 1}
+}`
+
+func parse(t *testing.T) *View {
+	t.Helper()
+	v, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// str renders a scalar as kind:value.
+func str(s log.Scalar) string {
+	switch s.Tag() {
+	case 0:
+		return "str:" + *s.Str()
+	case 1:
+		return fmt.Sprintf("int:%d", *s.Int())
+	case 2:
+		return fmt.Sprintf("float:%v", *s.Float())
+	case 3:
+		return fmt.Sprintf("bool:%t", *s.Boolean())
+	default:
+		return fmt.Sprintf("bytes:%x", s.Bytes().Slice())
+	}
+}
+
+func TestGet(t *testing.T) {
+	v := parse(t)
+	tests := map[string]string{
+		"s":            "str:str",
+		"i":            "int:42",
+		"neg":          "int:-7",
+		"f":            "float:1.5",
+		"whole":        "float:2",
+		"exp":          "float:1000",
+		"big":          "float:1.8446744073709552e+19",
+		"t":            "bool:true",
+		"obj.a":        "str:x",
+		"obj.m.k":      "int:1",
+		"list[1]":      "str:two",
+		"list[5].k":    "str:v",
+		"list[6][0]":   "int:9",
+		"nested[1][0]": "int:3",
+		`["k.dot"].v`:  "int:1",
+	}
+	for path, want := range tests {
+		opt := v.Get(path)
+		if opt.None() {
+			t.Errorf("Get(%s) = none, want %s", path, want)
+		} else if got := str(opt.Value()); got != want {
+			t.Errorf("Get(%s) = %s, want %s", path, got, want)
+		}
+	}
+
+	// Containers and null have no scalar form; bad paths resolve to nothing.
+	for _, path := range []string{
+		"", "n", "obj", "empty", "list", "list[4]", "obj.null",
+		"missing", "s.x", "obj[0]", "list.k", "list[7]", "list[-1]", "list[99999999999]",
+		"obj.", "obj..a", ".obj", "list[", `["k.dot"`, "k.dot.v",
+	} {
+		if opt := v.Get(path); !opt.None() {
+			t.Errorf("Get(%q) = %s, want none", path, str(opt.Value()))
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	v := parse(t)
+	for path, want := range map[string]bool{
+		"": true, "n": true, "obj.null": true, "empty": true, "none": true, "list[4]": true,
+		"missing": false, "list[7]": false, "obj.": false, "list[-1]": false, "s[0]": false,
+	} {
+		if got := v.Has(path); got != want {
+			t.Errorf("Has(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestGetList(t *testing.T) {
+	v := parse(t)
+	tests := []struct {
+		path string
+		want []string // nil for none
+	}{
+		// Elements without a scalar form are left out.
+		{"list", []string{"int:1", "str:two", "float:3.5", "bool:false"}},
+		{"nested[0]", []string{"int:1", "int:2"}},
+		{"obj.l", []string{"int:1"}},
+		{"none", []string{}},
+		{"obj", nil},
+		{"s", nil},
+		{"missing", nil},
+		{"list[", nil},
+	}
+	for _, tt := range tests {
+		opt := v.GetList(tt.path)
+		if tt.want == nil {
+			if !opt.None() {
+				t.Errorf("GetList(%s) is some", tt.path)
+			}
+			continue
+		}
+		if opt.None() {
+			t.Errorf("GetList(%s) = none", tt.path)
+			continue
+		}
+		var got []string
+		for _, s := range opt.Value().Slice() {
+			got = append(got, str(s))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GetList(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGetMap(t *testing.T) {
+	v := parse(t)
+	tests := []struct {
+		path string
+		want []string // nil for none
+	}{
+		// Members without a scalar form are left out; keys are sorted.
+		{"obj", []string{"a=str:x", "z=int:1"}},
+		{`["k.dot"]`, []string{"v=int:1"}},
+		{"empty", []string{}},
+		{"list", nil},
+		{"s", nil},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		opt := v.GetMap(tt.path)
+		if tt.want == nil {
+			if !opt.None() {
+				t.Errorf("GetMap(%s) is some", tt.path)
+			}
+			continue
+		}
+		if opt.None() {
+			t.Errorf("GetMap(%s) = none", tt.path)
+			continue
+		}
+		var got []string
+		for _, f := range opt.Value().Slice() {
+			got = append(got, f.F0+"="+str(f.F1))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GetMap(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestKeysAndLen(t *testing.T) {
+	v := parse(t)
+	tests := []struct {
+		path string
+		keys []string
+		len  int // -1 for none
+	}{
+		{"", []string{"big", "empty", "exp", "f", "i", "k.dot", "list", "n", "neg", "nested", "none", "obj", "s", "t", "whole"}, 15},
+		{"obj", []string{"a", "l", "m", "null", "z"}, 5},
+		{"empty", []string{}, 0},
+		{"list", []string{}, 7},
+		{"none", []string{}, 0},
+		{"list[5]", []string{"k"}, 1},
+		{"s", []string{}, -1},
+		{"n", []string{}, -1},
+		{"missing", []string{}, -1},
+		{"obj.", []string{}, -1},
+	}
+	for _, tt := range tests {
+		if got := v.Keys(tt.path).Slice(); !slices.Equal(got, tt.keys) {
+			t.Errorf("Keys(%q) = %q, want %q", tt.path, got, tt.keys)
+		}
+		opt := v.Len(tt.path)
+		switch {
+		case tt.len < 0 && !opt.None():
+			t.Errorf("Len(%q) = %d, want none", tt.path, opt.Value())
+		case tt.len >= 0 && (opt.None() || opt.Value() != uint32(tt.len)):
+			t.Errorf("Len(%q) = %v, want %d", tt.path, opt, tt.len)
+		}
+	}
+}
+
+func TestParseAndFromValue(t *testing.T) {
+	for _, raw := range []string{"", "{", `{"a":1} {"b":2}`, `{"a":1}x`} {
+		if _, err := Parse([]byte(raw)); err == nil {
+			t.Errorf("Parse(%q) succeeded", raw)
+		}
+	}
+	v, err := Parse([]byte("  {\"a\": 1}\n"))
+	if err != nil || v.Log() != `{"a": 1}` {
+		t.Errorf("Parse = %v, %v; Log() = %q", v, err, v.Log())
+	}
+
+	v, err = FromValue(map[string]any{"i": 3, "f": 0.5, "l": []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := str(v.Get("i").Value()); got != "int:3" {
+		t.Errorf("FromValue: Get(i) = %s", got)
+	}
+	if got := str(v.Get("f").Value()); got != "float:0.5" {
+		t.Errorf("FromValue: Get(f) = %s", got)
+	}
+	if _, err := FromValue(math.NaN()); err == nil {
+		t.Error("FromValue(NaN) succeeded")
+	}
+}
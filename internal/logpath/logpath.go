@@ -0,0 +1,136 @@
+// Package logpath parses dot/bracket field paths such as
+// "detail.findings[0].CompanyName" or `tags["k8s.io/name"]`, for the SDK's
+// in-process log sources and selector checks. The host parses paths itself;
+// this grammar is the SDK's reading of it.
+package logpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment is one step of a parsed path: either an object key or a list index.
+type Segment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Error reports a malformed path. Offset is the byte offset of the problem.
+type Error struct {
+	Path   string
+	Offset int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid path %q at offset %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+// Parse splits path into segments. The empty path addresses the document root
+// and yields no segments.
+func Parse(path string) ([]Segment, error) {
+	var segs []Segment
+	i := 0
+	fail := func(off int, msg string) ([]Segment, error) {
+		return nil, &Error{Path: path, Offset: off, Msg: msg}
+	}
+	for i < len(path) {
+		switch c := path[i]; {
+		case c == '[':
+			j := i + 1
+			if j < len(path) && path[j] == '"' {
+				key, n, err := unquote(path[j:])
+				if err != nil {
+					return fail(j, err.Error())
+				}
+				j += n
+				if j >= len(path) || path[j] != ']' {
+					return fail(j, "expected ']'")
+				}
+				segs = append(segs, Segment{Key: key})
+				i = j + 1
+				break
+			}
+			end := strings.IndexByte(path[j:], ']')
+			if end < 0 {
+				return fail(i, "unterminated '['")
+			}
+			idx, err := strconv.Atoi(path[j : j+end])
+			if err != nil || idx < 0 {
+				return fail(j, "index must be a non-negative integer")
+			}
+			segs = append(segs, Segment{Index: idx, IsIndex: true})
+			i = j + end + 1
+		case c == '.':
+			if i == 0 || i == len(path)-1 {
+				return fail(i, "empty key")
+			}
+			if path[i+1] == '.' || path[i+1] == '[' {
+				return fail(i+1, "empty key")
+			}
+			i++
+		default:
+			if i > 0 && path[i-1] != '.' {
+				return fail(i, "expected '.' or '['")
+			}
+			j := i
+			for j < len(path) && path[j] != '.' && path[j] != '[' {
+				if path[j] == ']' {
+					return fail(j, "unexpected ']'")
+				}
+				j++
+			}
+			segs = append(segs, Segment{Key: path[i:j]})
+			i = j
+		}
+	}
+	return segs, nil
+}
+
+// Format renders segments back into the canonical path form accepted by Parse.
+func Format(segs []Segment) string {
+	var b strings.Builder
+	for i, s := range segs {
+		switch {
+		case s.IsIndex:
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(s.Index))
+			b.WriteByte(']')
+		case NeedsQuote(s.Key):
+			b.WriteByte('[')
+			b.WriteString(strconv.Quote(s.Key))
+			b.WriteByte(']')
+		default:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s.Key)
+		}
+	}
+	return b.String()
+}
+
+// NeedsQuote reports whether key must use the bracketed, quoted form.
+func NeedsQuote(key string) bool {
+	return key == "" || strings.ContainsAny(key, ".[]\"")
+}
+
+// unquote reads a double-quoted string from the start of s and returns its
+// value and the number of bytes consumed.
+func unquote(s string) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("bad quoted key")
+			}
+			return v, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted key")
+}
@@ -0,0 +1,98 @@
+package logpath
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func key(k string) Segment { return Segment{Key: k} }
+func idx(i int) Segment    { return Segment{Index: i, IsIndex: true} }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		path string
+		want []Segment
+	}{
+		{"", nil},
+		{"a", []Segment{key("a")}},
+		{"a.b.c", []Segment{key("a"), key("b"), key("c")}},
+		{"a[0]", []Segment{key("a"), idx(0)}},
+		{"[3]", []Segment{idx(3)}},
+		{"a[10][2].b", []Segment{key("a"), idx(10), idx(2), key("b")}},
+		{"a[007]", []Segment{key("a"), idx(7)}},
+		{`["a.b"]`, []Segment{key("a.b")}},
+		{`tags["k8s.io/name"].v`, []Segment{key("tags"), key("k8s.io/name"), key("v")}},
+		{`m[""]`, []Segment{key("m"), key("")}},
+		{`m["[0]"]`, []Segment{key("m"), key("[0]")}},
+		{`m["q\"uote"]`, []Segment{key("m"), key(`q"uote`)}},
+		{`m["é"]`, []Segment{key("m"), key("é")}},
+		{"a b.c-d", []Segment{key("a b"), key("c-d")}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.path)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, %v; want %v", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		path   string
+		offset int
+	}{
+		{".", 0},
+		{".a", 0},
+		{"a.", 1},
+		{"a..b", 2},
+		{"a.[0]", 2},
+		{"a[-1]", 2},
+		{"a[x]", 2},
+		{"a[]", 2},
+		{"a[1.5]", 2},
+		{"a[99999999999999999999]", 2},
+		{"a[0", 1},
+		{"a]", 1},
+		{"a[0]b", 4},
+		{`a["b"`, 5},
+		{`a["b]`, 2},
+		{`a["b"x]`, 5},
+		{`a["\q"]`, 2},
+	}
+	for _, tt := range tests {
+		segs, err := Parse(tt.path)
+		var pe *Error
+		if !errors.As(err, &pe) {
+			t.Errorf("Parse(%q) = %v, %v; want an *Error", tt.path, segs, err)
+			continue
+		}
+		if pe.Path != tt.path || pe.Offset != tt.offset {
+			t.Errorf("Parse(%q) error %q at offset %d, want offset %d", tt.path, pe.Msg, pe.Offset, tt.offset)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		segs []Segment
+		want string
+	}{
+		{nil, ""},
+		{[]Segment{key("a"), key("b")}, "a.b"},
+		{[]Segment{idx(0), key("a")}, "[0].a"},
+		{[]Segment{key("a"), idx(1), idx(2)}, "a[1][2]"},
+		{[]Segment{key("a.b"), key("c")}, `["a.b"].c`},
+		{[]Segment{key("m"), key(""), key(`q"`)}, `m[""]["q\""]`},
+		{[]Segment{key("x"), key("[y]")}, `x["[y]"]`},
+	}
+	for _, tt := range tests {
+		got := Format(tt.segs)
+		if got != tt.want {
+			t.Errorf("Format(%v) = %q, want %q", tt.segs, got, tt.want)
+		}
+		if back, err := Parse(got); err != nil || !reflect.DeepEqual(back, tt.segs) {
+			t.Errorf("Parse(Format(%v)) = %v, %v", tt.segs, back, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// Package logsource defines the host calls a tangent_sdk.Log is built on.
+package logsource
+
+import (
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"go.bytecodealliance.org/cm"
+)
+
+// Source mirrors the method set of the host's log.Logview resource so that
+// in-process implementations can stand in for it outside a Tangent host.
+type Source interface {
+	Get(path string) cm.Option[log.Scalar]
+	GetList(path string) cm.Option[cm.List[log.Scalar]]
+	GetMap(path string) cm.Option[cm.List[cm.Tuple[string, log.Scalar]]]
+	Has(path string) bool
+	Keys(path string) cm.List[string]
+	Len(path string) cm.Option[uint32]
+	Log() string
+	ResourceDrop()
+}
+
+var _ Source = log.Logview(0)
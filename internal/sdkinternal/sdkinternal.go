@@ -0,0 +1,9 @@
+// Package sdkinternal gives other packages in this module access to
+// tangent_sdk functionality that is not part of its public API.
+package sdkinternal
+
+import "github.com/telophasehq/tangent-sdk-go/internal/logsource"
+
+// NewLog wraps src in a tangent_sdk.Log, returned as any to avoid an import
+// cycle. tangent_sdk sets it during initialization.
+var NewLog func(src logsource.Source) any
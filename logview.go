@@ -1,9 +1,24 @@
 package tangent_sdk
 
-import "github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+import (
+	"github.com/telophasehq/tangent-sdk-go/internal/logsource"
+	"github.com/telophasehq/tangent-sdk-go/internal/sdkinternal"
+)
 
+// Log is a read-only view of a single log record. Inside a Tangent host it is
+// backed by the host's logview resource; tangenttest backs it with an
+// in-memory JSON document.
 type Log struct {
-	logview log.Logview
+	logview logsource.Source
+}
+
+func newLog(src logsource.Source) Log {
+	return Log{logview: src}
+}
+
+func init() {
+	// In-process hosts such as tangenttest construct logs through this.
+	sdkinternal.NewLog = func(src logsource.Source) any { return newLog(src) }
 }
 
 func (v Log) Log() string {
@@ -20,6 +35,31 @@ func (v Log) Keys(path string) []string {
 	return append([]string(nil), keys.Slice()...)
 }
 
+// Get returns the scalar at path. ok is false when path is missing or does not
+// hold a scalar.
+func (v Log) Get(path string) (Value, bool) {
+	opt := v.logview.Get(path)
+	if opt.None() {
+		return Value{}, false
+	}
+	return valueOf(opt.Value()), true
+}
+
+// GetMany returns the scalar at each of paths, in order. Missing and
+// non-scalar paths yield a zero Value; check IsValid. It asks the source once
+// per path, exactly as a loop over Get would; mappers that read a fixed set of
+// fields can keep the list in one place:
+//
+//	vals := l.GetMany(flowPaths)
+//	src, _ := vals[0].AsString()
+func (v Log) GetMany(paths []string) []Value {
+	out := make([]Value, len(paths))
+	for i, path := range paths {
+		out[i], _ = v.Get(path)
+	}
+	return out
+}
+
 func (v Log) GetBool(path string) *bool {
 	opt := v.logview.Get(path)
 	if opt.None() {
@@ -57,7 +97,12 @@ func (v Log) GetString(path string) *string {
 }
 
 func (v Log) Len(path string) *uint32 {
-	return v.Len(path)
+	opt := v.logview.Len(path)
+	if opt.None() {
+		return nil
+	}
+	n := opt.Value()
+	return &n
 }
 
 func (v Log) GetStringList(path string) ([]string, bool) {
@@ -107,3 +152,32 @@ func (v Log) GetInt64List(path string) ([]int64, bool) {
 	}
 	return out, true
 }
+
+// GetList returns the scalar elements of the list at path.
+func (v Log) GetList(path string) ([]Value, bool) {
+	opt := v.logview.GetList(path)
+	if opt.None() {
+		return nil, false
+	}
+	data := opt.Value().Slice()
+	out := make([]Value, len(data))
+	for i := range data {
+		out[i] = valueOf(data[i])
+	}
+	return out, true
+}
+
+// GetMap returns the scalar members of the object at path, in the order the
+// host reports them. Nested objects and lists are not included.
+func (v Log) GetMap(path string) (Fields, bool) {
+	opt := v.logview.GetMap(path)
+	if opt.None() {
+		return nil, false
+	}
+	data := opt.Value().Slice()
+	out := make(Fields, len(data))
+	for i := range data {
+		out[i] = Field{Key: data[i].F0, Value: valueOf(data[i].F1)}
+	}
+	return out, true
+}
@@ -0,0 +1,92 @@
+package tangent_sdk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+	"github.com/telophasehq/tangent-sdk-go/internal/logsource"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"go.bytecodealliance.org/cm"
+)
+
+const flowLog = `{"srcaddr":"10.0.0.1","dstaddr":"10.0.0.2","srcport":443,"dstport":51234,"bytes":8192,"action":"ACCEPT","tags":{"env":"prod"}}`
+
+var flowPaths = []string{"srcaddr", "dstaddr", "srcport", "dstport", "bytes", "action", "tags.env"}
+
+// countingSource counts the Get calls that reach src.
+type countingSource struct {
+	logsource.Source
+	gets int
+}
+
+func (c *countingSource) Get(path string) cm.Option[log.Scalar] {
+	c.gets++
+	return c.Source.Get(path)
+}
+
+func TestGetMany(t *testing.T) {
+	view, err := jsonlog.Parse([]byte(`{"a":"x","n":2,"obj":{"b":true},"list":[1.5]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &countingSource{Source: view}
+	l := newLog(src)
+
+	paths := []string{"a", "n", "missing", "obj", "obj.b", "list[0]", "a"}
+	got := l.GetMany(paths)
+	if src.gets != len(paths) {
+		t.Errorf("GetMany made %d Get calls, want %d", src.gets, len(paths))
+	}
+	if len(got) != len(paths) {
+		t.Fatalf("GetMany returned %d values, want %d", len(got), len(paths))
+	}
+	for i, path := range paths {
+		want, _ := l.Get(path)
+		if !reflect.DeepEqual(got[i].Interface(), want.Interface()) || got[i].IsValid() != want.IsValid() {
+			t.Errorf("GetMany[%d] (%s) = %v, want %v", i, path, got[i].Interface(), want.Interface())
+		}
+	}
+	for _, i := range []int{2, 3} {
+		if got[i].IsValid() {
+			t.Errorf("GetMany[%d] (%s) is valid, want the zero Value", i, paths[i])
+		}
+	}
+
+	if got := l.GetMany(nil); len(got) != 0 {
+		t.Errorf("GetMany(nil) = %v, want empty", got)
+	}
+	sub := l.Sub("obj").GetMany([]string{"b", "c"})
+	if b, ok := sub[0].AsBool(); !ok || !b || sub[1].IsValid() {
+		t.Errorf("Sub(obj).GetMany = %v, %v", sub[0].Interface(), sub[1].Interface())
+	}
+}
+
+// BenchmarkAccessors measures the SDK's side of typical field reads against
+// an in-process log. Host crossing costs are measured on a compiled plugin
+// with `run -bench`, which reports host calls per log by import.
+func BenchmarkAccessors(b *testing.B) {
+	l := testLogs(b, flowLog)[0]
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, p := range flowPaths {
+			if _, ok := l.Get(p); !ok {
+				b.Fatal(p)
+			}
+		}
+	}
+}
+
+// BenchmarkGetMany reads the same fields as BenchmarkAccessors in one call;
+// the difference is GetMany's own overhead.
+func BenchmarkGetMany(b *testing.B) {
+	l := testLogs(b, flowLog)[0]
+	b.ReportAllocs()
+	for b.Loop() {
+		for i, v := range l.GetMany(flowPaths) {
+			if !v.IsValid() {
+				b.Fatal(flowPaths[i])
+			}
+		}
+	}
+}
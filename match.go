@@ -0,0 +1,273 @@
+package tangent_sdk
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+)
+
+// regexCache holds compiled Regex predicate patterns.
+var regexCache sync.Map // string -> *regexp.Regexp (nil if invalid)
+
+func compileRegex(pattern string) *regexp.Regexp {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = nil
+	}
+	regexCache.Store(pattern, re)
+	return re
+}
+
+// Match reports whether l satisfies s, evaluating the predicates in the
+// plugin. It is meant for unit testing selectors and for WireRoutes, not as
+// a statement of what the host will send: the host evaluates probes with its
+// own implementation, which this one is not checked against. Locally,
+//
+//   - paths are resolved by the Log accessors, so a predicate sees what Get,
+//     Has and Len see;
+//   - Eq and In compare ints and floats numerically (1 == 1.0) and other
+//     kinds only with the same kind;
+//   - Prefix and Regex hold only for strings, and Gt only for numbers;
+//   - Regex uses Go's regexp (RE2 syntax), unanchored. The host's regex
+//     engine may disagree on some syntax, such as whether \d and \w match
+//     non-ASCII characters, so prefer plain ASCII classes in selectors that
+//     route.
+func (s Selector) Match(l Log) bool {
+	return s.matches(l)
+}
+
+// MatchJSON is like Match for a JSON document.
+func (s Selector) MatchJSON(doc []byte) (bool, error) {
+	view, err := jsonlog.Parse(doc)
+	if err != nil {
+		return false, err
+	}
+	return s.matches(newLog(view)), nil
+}
+
+// MatchAny returns the index of the first selector matching l, or -1.
+func MatchAny(selectors []Selector, l Log) int {
+	for i := range selectors {
+		if selectors[i].matches(l) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Explanation describes how a selector evaluated against a log.
+type Explanation struct {
+	Selector Selector
+	Matched  bool
+	// Failed lists the predicates that stopped the selector matching: All
+	// predicates that were false, None predicates that were true and, if no
+	// Any predicate was true, every Any predicate.
+	Failed []PredicateResult
+}
+
+// PredicateResult is the outcome of one predicate in an Explanation.
+type PredicateResult struct {
+	Group     string // "any", "all" or "none"
+	Index     int    // position within the group
+	Predicate Predicate
+	Matched   bool
+	Actual    string // the log's value at the predicate's path, or "missing"
+}
+
+func (r PredicateResult) String() string {
+	want := "false"
+	if r.Group != "none" {
+		want = "true"
+	}
+	return fmt.Sprintf("%s[%d] %s: want %s, got %t (%s)", r.Group, r.Index, r.Predicate, want, r.Matched, r.Actual)
+}
+
+func (e Explanation) String() string {
+	if e.Matched {
+		return fmt.Sprintf("%s: matched", e.Selector)
+	}
+	lines := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		lines[i] = "  " + r.String()
+	}
+	return fmt.Sprintf("%s: no match\n%s", e.Selector, strings.Join(lines, "\n"))
+}
+
+// Explain evaluates s against l and reports which predicates kept it from
+// matching:
+//
+//	fmt.Println(sel.Explain(tangenttest.MustLog(sample)))
+//	// source == "aws.guardduty" && !(region =~ "^cn-"): no match
+//	//   none[0] region =~ "^cn-": want false, got true ("cn-north-1")
+func (s Selector) Explain(l Log) Explanation {
+	e := Explanation{Selector: s, Matched: s.matches(l)}
+	result := func(group string, i int, p Predicate) PredicateResult {
+		return PredicateResult{Group: group, Index: i, Predicate: p, Matched: p.matches(l), Actual: actual(l, p)}
+	}
+	for i, p := range s.All {
+		if r := result("all", i, p); !r.Matched {
+			e.Failed = append(e.Failed, r)
+		}
+	}
+	for i, p := range s.None {
+		if r := result("none", i, p); r.Matched {
+			e.Failed = append(e.Failed, r)
+		}
+	}
+	var anys []PredicateResult
+	for i, p := range s.Any {
+		r := result("any", i, p)
+		if r.Matched {
+			anys = nil
+			break
+		}
+		anys = append(anys, r)
+	}
+	e.Failed = append(e.Failed, anys...)
+	return e
+}
+
+// actual describes the value at p's path in l.
+func actual(l Log, p Predicate) string {
+	if len(p.terms) == 0 {
+		return "no terms"
+	}
+	path := p.terms[0].path
+	if v, ok := l.Get(path); ok {
+		switch v.Kind() {
+		case KindString:
+			s, _ := v.AsString()
+			return strconv.Quote(s)
+		case KindBytes:
+			b, _ := v.AsBytes()
+			return fmt.Sprintf("bytes %q", b)
+		}
+		return fmt.Sprint(v.Interface())
+	}
+	if n := l.Len(path); n != nil {
+		return fmt.Sprintf("non-scalar of length %d", *n)
+	}
+	if l.Has(path) {
+		return "null"
+	}
+	return "missing"
+}
+
+// matches reports whether l satisfies s: at least one Any predicate (if
+// there are any), every All predicate and no None predicate.
+func (s Selector) matches(l Log) bool {
+	for _, p := range s.All {
+		if !p.matches(l) {
+			return false
+		}
+	}
+	for _, p := range s.None {
+		if p.matches(l) {
+			return false
+		}
+	}
+	if len(s.Any) == 0 {
+		return true
+	}
+	for _, p := range s.Any {
+		if p.matches(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether l satisfies every term of p.
+func (p Predicate) matches(l Log) bool {
+	for _, t := range p.terms {
+		if !t.matches(l) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches evaluates t against l (see Selector.Match).
+func (t term) matches(l Log) bool {
+	return t.hostMatches(l) != t.negate
+}
+
+func (t term) hostMatches(l Log) bool {
+	if t.op == opHas {
+		return l.Has(t.path)
+	}
+	v, ok := l.Get(t.path)
+	return ok && t.valueMatches(v)
+}
+
+// valueMatches reports whether the host predicate t holds when its path is
+// the scalar v.
+func (t term) valueMatches(v Value) bool {
+	switch t.op {
+	case opHas:
+		return true
+	case opEq, opIn:
+		for _, want := range t.values {
+			if scalarEqual(v, valueOf(want)) {
+				return true
+			}
+		}
+		return false
+	case opPrefix:
+		s, ok := v.AsString()
+		return ok && strings.HasPrefix(s, t.arg)
+	case opGt:
+		f, ok := asFloat(v)
+		return ok && f > t.num
+	case opRegex:
+		s, ok := v.AsString()
+		re := compileRegex(t.arg)
+		return ok && re != nil && re.MatchString(s)
+	}
+	return false
+}
+
+// scalarEqual compares two scalars, treating ints and floats numerically.
+func scalarEqual(a, b Value) bool {
+	switch a.Kind() {
+	case KindString:
+		x, _ := a.AsString()
+		y, ok := b.AsString()
+		return ok && x == y
+	case KindBool:
+		x, _ := a.AsBool()
+		y, ok := b.AsBool()
+		return ok && x == y
+	case KindBytes:
+		x, _ := a.AsBytes()
+		y, ok := b.AsBytes()
+		return ok && string(x) == string(y)
+	case KindInt, KindFloat:
+		if x, ok := a.AsInt(); ok {
+			if y, ok := b.AsInt(); ok {
+				return x == y
+			}
+		}
+		x, _ := asFloat(a)
+		y, ok := asFloat(b)
+		return ok && x == y
+	}
+	return false
+}
+
+func asFloat(v Value) (float64, bool) {
+	if f, ok := v.AsFloat(); ok {
+		return f, true
+	}
+	if i, ok := v.AsInt(); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
@@ -0,0 +1,109 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestMatchSemantics pins the local evaluation rules documented at
+// Selector.Match.
+func TestMatchSemantics(t *testing.T) {
+	doc := `{"i":1,"f":1.0,"g":1.5,"s":"1","b":true,"n":null,"u":"é1","obj":{"k":"v"},"list":[1,2]}`
+	l := testLogs(t, doc)[0]
+	tests := []struct {
+		p    Predicate
+		want bool
+	}{
+		{Has("i"), true},
+		{Has("n"), true},
+		{Has("obj"), true},
+		{Has("missing"), false},
+		{EqInt("i", 1), true},
+		{EqFloat("i", 1), true},
+		{EqInt("f", 1), true},
+		{EqFloat("g", 1.5), true},
+		{EqInt("g", 1), false},
+		{EqString("i", "1"), false},
+		{EqInt("s", 1), false},
+		{EqBool("b", true), true},
+		{EqString("b", "true"), false},
+		{EqInt("list[1]", 2), true},
+		{EqString("obj", "v"), false},
+		{EqBool("n", false), false},
+		{InInts("f", 2, 1), true},
+		{InFloats("i", 1), true},
+		{InStrings("i", "1"), false},
+		{Prefix("s", "1"), true},
+		{Prefix("i", "1"), false},
+		{Gt("i", 0.5), true},
+		{Gt("s", 0), false},
+		{Gte("f", 1), true},
+		{Lt("s", 5), false},
+		{Lt("missing", 5), false},
+		{Lte("g", 1.5), true},
+		{Between("g", 1, 2), true},
+		{Regex("s", "1"), true},
+		{Regex("u", "1$"), true},
+		{Regex("i", "1"), false},
+		{Regex("u", "^.1$"), true},
+		{Regex("u", `^\w`), false}, // ASCII \w, as in Go's regexp
+		{Regex("s", "("), false},
+	}
+	for _, tt := range tests {
+		sel := Selector{All: []Predicate{tt.p}}
+		if got := sel.Match(l); got != tt.want {
+			t.Errorf("%s on %s = %t, want %t", tt.p, doc, got, tt.want)
+		}
+		neg := Selector{None: []Predicate{tt.p}}
+		if got := neg.Match(l); got == tt.want {
+			t.Errorf("!(%s) on %s = %t, want %t", tt.p, doc, got, !tt.want)
+		}
+	}
+}
+
+func TestMatchGroups(t *testing.T) {
+	l := testLogs(t, `{"a":1,"b":"x"}`)[0]
+	tests := []struct {
+		sel  Selector
+		want bool
+	}{
+		{Selector{}, true},
+		{Selector{Any: []Predicate{Has("z"), Has("a")}}, true},
+		{Selector{Any: []Predicate{Has("z")}}, false},
+		{Selector{All: []Predicate{Has("a"), Has("z")}}, false},
+		{Selector{All: []Predicate{Has("a")}, None: []Predicate{EqString("b", "x")}}, false},
+		{Selector{Any: []Predicate{Has("a")}, None: []Predicate{Has("z")}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.sel.Match(l); got != tt.want {
+			t.Errorf("%s = %t, want %t", tt.sel, got, tt.want)
+		}
+		if got := MatchAny([]Selector{{Any: []Predicate{Has("z")}}, tt.sel}, l) == 1; got != tt.want {
+			t.Errorf("MatchAny with %s = %t, want %t", tt.sel, got, tt.want)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	handle := dispatch([]Binding{
+		Route(EqString("type", "a"), func(l Log) (string, error) { return "first", nil }),
+		Route(Prefix("type", "a"), func(l Log) (string, error) { return "second", nil }),
+		RouteMulti(Has("type"), func(l Log) ([]string, error) { return nil, ErrSkip }),
+	})
+	tests := []struct {
+		doc  string
+		want []any
+		err  error
+	}{
+		{`{"type":"a"}`, []any{"first"}, nil},
+		{`{"type":"ab"}`, []any{"second"}, nil},
+		{`{"type":"b"}`, nil, ErrSkip},
+		{`{}`, nil, ErrNoRoute},
+	}
+	for _, tt := range tests {
+		out, err := handle(testLogs(t, tt.doc)[0])
+		if !errors.Is(err, tt.err) || len(out) != len(tt.want) || len(out) > 0 && out[0] != tt.want[0] {
+			t.Errorf("%s: got %v, %v; want %v, %v", tt.doc, out, err, tt.want, tt.err)
+		}
+	}
+}
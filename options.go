@@ -0,0 +1,58 @@
+package tangent_sdk
+
+import "bytes"
+
+// Option configures Wire and WireMulti.
+type Option func(*options)
+
+type options struct {
+	deadLetter        func(DeadLetter)
+	inlineDeadLetters bool
+	encoder           Encoder
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{encoder: NDJSON}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.inlineDeadLetters {
+		// Fail at Wire, during plugin load, rather than on every batch.
+		if err := o.encoder.Encode(new(bytes.Buffer), inlineDeadLetter{}); err != nil {
+			panic("tangent: WithInlineDeadLetters is not supported by the selected encoder: " + err.Error())
+		}
+	}
+	return o
+}
+
+// isolate reports whether a failing log should be set aside instead of
+// failing its whole batch.
+func (o *options) isolate() bool {
+	return o.deadLetter != nil || o.inlineDeadLetters
+}
+
+// WithDeadLetter isolates handler errors per log: each log whose handler
+// fails is passed to fn and the rest of the batch is still emitted.
+//
+// A batch handler sees the whole batch, so when it fails the batch is run
+// again one log at a time to find the failing logs. A failed batch of n logs
+// therefore costs n+1 handler calls, and any side effects of the handler are
+// repeated for the logs that succeed on the retry.
+func WithDeadLetter(fn func(DeadLetter)) Option {
+	return func(o *options) { o.deadLetter = fn }
+}
+
+// WithInlineDeadLetters isolates handler errors per log like WithDeadLetter,
+// writing each failure into the output as a record of the form
+//
+//	{"dead_letter":{"index":3,"error":"...","log":"..."}}
+//
+// so the host receives failures alongside the batch's good records. Batch
+// handlers are retried per log as described for WithDeadLetter.
+//
+// Inline dead letters are supported by the NDJSON, JSON and MessagePack
+// encoders. Wire panics if the option is combined with an encoder that can't
+// write them, such as ProtobufDelimited.
+func WithInlineDeadLetters() Option {
+	return func(o *options) { o.inlineDeadLetters = true }
+}
@@ -0,0 +1,52 @@
+package tangent_sdk
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError reports a panic recovered from a handler.
+//
+// Recovery depends on the runtime. With the Go toolchain, including go test
+// with tangenttest, a handler panic fails the batch (or, with WithDeadLetter,
+// just the log) with a *PanicError. TinyGo's wasm targets do not support
+// recover: a panicking handler still traps the instance, and the host reports
+// the trap instead. Where recover works but the runtime keeps no stack
+// traces, Stack is empty.
+type PanicError struct {
+	Plugin string // Metadata.Name of the mapper
+	Index  int    // position of the offending log in its batch, or -1 if a batch handler panicked
+	Value  any    // value passed to panic
+	Stack  []byte // goroutine stack at the time of the panic, if available
+}
+
+func (e *PanicError) Error() string {
+	where := "batch"
+	if e.Index >= 0 {
+		where = fmt.Sprintf("log %d", e.Index)
+	}
+	msg := fmt.Sprintf("panic processing %s: %v", where, e.Value)
+	if len(e.Stack) > 0 {
+		msg += "\n" + string(e.Stack)
+	}
+	if e.Plugin != "" {
+		msg = e.Plugin + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic turns a panic into a *PanicError stored in *err. It must be
+// deferred directly.
+func recoverPanic(plugin string, index int, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	*err = &PanicError{Plugin: plugin, Index: index, Value: v, Stack: debug.Stack()}
+}
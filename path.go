@@ -0,0 +1,21 @@
+package tangent_sdk
+
+import (
+	"strconv"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/logpath"
+)
+
+// fieldPath returns the path of the object member name under p. Names
+// containing '.', '[', ']' or '"' are quoted.
+func fieldPath(p, name string) string {
+	if logpath.NeedsQuote(name) {
+		return p + "[" + strconv.Quote(name) + "]"
+	}
+	return joinPath(p, name)
+}
+
+// indexPath returns the path of the i'th element of the list at p.
+func indexPath(p string, i int) string {
+	return p + "[" + strconv.Itoa(i) + "]"
+}
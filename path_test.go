@@ -0,0 +1,27 @@
+package tangent_sdk
+
+import (
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/logpath"
+)
+
+func TestPathBuilders(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{fieldPath("", "a"), "a"},
+		{fieldPath(indexPath("detail.findings", 0), "CompanyId"), "detail.findings[0].CompanyId"},
+		{fieldPath("tags", "k8s.io/name"), `tags["k8s.io/name"]`},
+		{fieldPath("", `say "hi"`), `["say \"hi\""]`},
+		{indexPath("", 2), "[2]"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+		if _, err := logpath.Parse(tt.want); err != nil {
+			t.Errorf("logpath.Parse(%q): %v", tt.want, err)
+		}
+	}
+}
@@ -1,74 +1,274 @@
 package tangent_sdk
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
 	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
 	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/mapper"
 
 	"go.bytecodealliance.org/cm"
 )
 
-// Predicate represents a single probe predicate.
+// Predicate represents a single probe predicate. Most predicates map to one
+// host predicate; range predicates the host can't express natively are a
+// conjunction of host predicates, some negated.
 type Predicate struct {
-	inner mapper.Pred
+	terms []term
+	desc  string // selector syntax for compound predicates
+}
+
+// predOp identifies a host predicate case.
+type predOp uint8
+
+const (
+	opHas predOp = iota
+	opEq
+	opPrefix
+	opIn
+	opGt
+	opRegex
+)
+
+// term is a host predicate, possibly negated. It is kept in Go form and
+// converted to mapper.Pred only for the probe export.
+type term struct {
+	op      predOp
+	path    string
+	arg     string       // prefix or regex pattern
+	num     float64      // gt threshold
+	bound   float64      // with atLeast: the caller's bound, just above num
+	atLeast bool         // a gt term written as "path >= bound", or "path < bound" when negated
+	values  []log.Scalar // eq value or in set
+	negate  bool
+}
+
+func pred(t term) Predicate {
+	return Predicate{terms: []term{t}}
+}
+
+// simple reports whether p is a single un-negated host predicate.
+func (p Predicate) simple() bool {
+	return len(p.terms) == 1 && !p.terms[0].negate
+}
+
+func (t term) toMapper() mapper.Pred {
+	switch t.op {
+	case opEq:
+		return mapper.PredEq(cm.Tuple[string, mapper.Scalar]{F0: t.path, F1: t.values[0]})
+	case opPrefix:
+		return mapper.PredPrefix([2]string{t.path, t.arg})
+	case opIn:
+		return mapper.PredIn(cm.Tuple[string, cm.List[mapper.Scalar]]{F0: t.path, F1: cm.ToList(t.values)})
+	case opGt:
+		return mapper.PredGt(cm.Tuple[string, float64]{F0: t.path, F1: t.num})
+	case opRegex:
+		return mapper.PredRegex([2]string{t.path, t.arg})
+	default:
+		return mapper.PredHas(t.path)
+	}
 }
 
 // Has returns a predicate that matches when path exists.
 func Has(path string) Predicate {
-	return Predicate{inner: mapper.PredHas(path)}
+	return pred(term{op: opHas, path: path})
 }
 
 // EqString matches when the field at path equals value.
-func EqString(path, value string) Predicate {
-	return Predicate{inner: mapper.PredEq(cm.Tuple[string, mapper.Scalar]{
-		F0: path,
-		F1: log.ScalarStr(value),
-	})}
+func EqString(path string, value string) Predicate {
+	return eq(path, log.ScalarStr(value))
 }
 
 // EqInt matches when the field at path equals value.
 func EqInt(path string, value int64) Predicate {
-	return Predicate{inner: mapper.PredEq(cm.Tuple[string, mapper.Scalar]{
-		F0: path,
-		F1: log.ScalarInt(value),
-	})}
+	return eq(path, log.ScalarInt(value))
 }
 
 // EqFloat matches when the field at path equals value.
 func EqFloat(path string, value float64) Predicate {
-	return Predicate{inner: mapper.PredEq(cm.Tuple[string, mapper.Scalar]{
-		F0: path,
-		F1: log.ScalarFloat(value),
-	})}
+	return eq(path, log.ScalarFloat(value))
 }
 
 // EqBool matches when the field at path equals value.
 func EqBool(path string, value bool) Predicate {
-	return Predicate{inner: mapper.PredEq(cm.Tuple[string, mapper.Scalar]{
-		F0: path,
-		F1: log.ScalarBoolean(value),
-	})}
+	return eq(path, log.ScalarBoolean(value))
+}
+
+func eq(path string, value log.Scalar) Predicate {
+	return pred(term{op: opEq, path: path, values: []log.Scalar{value}})
 }
 
 // Prefix matches when the field at path has the given prefix.
-func Prefix(path, prefix string) Predicate {
-	return Predicate{inner: mapper.PredPrefix([2]string{path, prefix})}
+func Prefix(path string, prefix string) Predicate {
+	return pred(term{op: opPrefix, path: path, arg: prefix})
 }
 
 // Regex matches when the field at path matches pattern.
-func Regex(path, pattern string) Predicate {
-	return Predicate{inner: mapper.PredRegex([2]string{path, pattern})}
+func Regex(path string, pattern string) Predicate {
+	return pred(term{op: opRegex, path: path, arg: pattern})
 }
 
 // InStrings matches when the field at path is one of values.
 func InStrings(path string, values ...string) Predicate {
-	scalars := make([]mapper.Scalar, len(values))
+	return in(path, values, log.ScalarStr)
+}
+
+// InInts matches when the field at path is one of values.
+func InInts(path string, values ...int64) Predicate {
+	return in(path, values, log.ScalarInt)
+}
+
+// InFloats matches when the field at path is one of values.
+func InFloats(path string, values ...float64) Predicate {
+	return in(path, values, log.ScalarFloat)
+}
+
+// InBools matches when the field at path is one of values.
+func InBools(path string, values ...bool) Predicate {
+	return in(path, values, log.ScalarBoolean)
+}
+
+func in[V any](path string, values []V, scalar func(V) log.Scalar) Predicate {
+	scalars := make([]log.Scalar, len(values))
 	for i := range values {
-		scalars[i] = log.ScalarStr(values[i])
+		scalars[i] = scalar(values[i])
+	}
+	return pred(term{op: opIn, path: path, values: scalars})
+}
+
+// Gt matches when the number at path is greater than value.
+func Gt(path string, value float64) Predicate {
+	return pred(gt(path, value))
+}
+
+// Gte matches when the number at path is greater than or equal to value.
+func Gte(path string, value float64) Predicate {
+	return pred(gte(path, value))
+}
+
+// Lt matches when the number at path is less than value.
+func Lt(path string, value float64) Predicate {
+	p := numberAnd(path, not(gte(path, value)))
+	p.desc = fmt.Sprintf("%s < %s", path, formatFloat(value))
+	return p
+}
+
+// Lte matches when the number at path is less than or equal to value.
+func Lte(path string, value float64) Predicate {
+	p := numberAnd(path, not(gt(path, value)))
+	p.desc = fmt.Sprintf("%s <= %s", path, formatFloat(value))
+	return p
+}
+
+// Between matches when the number at path is in the closed range [lo, hi].
+func Between(path string, lo, hi float64) Predicate {
+	return Predicate{
+		terms: []term{
+			gte(path, lo),
+			not(gt(path, hi)),
+		},
+		desc: fmt.Sprintf("between(%s, %s, %s)", path, formatFloat(lo), formatFloat(hi)),
+	}
+}
+
+func gt(path string, value float64) term {
+	return term{op: opGt, path: path, num: value}
+}
+
+// gte is "path >= value": the host only has Gt, so it compares against the
+// next float below value.
+func gte(path string, value float64) term {
+	t := gt(path, math.Nextafter(value, math.Inf(-1)))
+	t.bound, t.atLeast = value, true
+	return t
+}
+
+func not(t term) term {
+	t.negate = !t.negate
+	return t
+}
+
+// numberAnd returns a predicate requiring a number at path as well as t, so
+// that a negated comparison doesn't match missing or non-numeric fields.
+func numberAnd(path string, t term) Predicate {
+	return Predicate{terms: []term{gt(path, math.Inf(-1)), t}}
+}
+
+// String returns p in selector syntax (see ParseExpr).
+func (p Predicate) String() string {
+	if p.desc != "" {
+		return p.desc
+	}
+	parts := make([]string, len(p.terms))
+	for i, t := range p.terms {
+		parts[i] = t.String()
 	}
-	return Predicate{inner: mapper.PredIn(cm.Tuple[string, cm.List[mapper.Scalar]]{
-		F0: path,
-		F1: cm.ToList(scalars),
-	})}
+	return strings.Join(parts, " && ")
+}
+
+func (t term) String() string {
+	var s string
+	switch t.op {
+	case opHas:
+		s = fmt.Sprintf("has(%s)", t.path)
+	case opEq:
+		if t.negate {
+			return fmt.Sprintf("%s != %s", t.path, formatScalar(t.values[0]))
+		}
+		return fmt.Sprintf("%s == %s", t.path, formatScalar(t.values[0]))
+	case opPrefix:
+		s = fmt.Sprintf("prefix(%s, %s)", t.path, strconv.Quote(t.arg))
+	case opIn:
+		vals := make([]string, len(t.values))
+		for i, v := range t.values {
+			vals[i] = formatScalar(v)
+		}
+		s = fmt.Sprintf("%s in [%s]", t.path, strings.Join(vals, ", "))
+	case opGt:
+		op, num := ">", t.num
+		if t.atLeast {
+			op, num = ">=", t.bound
+		}
+		if t.negate {
+			op = map[string]string{">": "<=", ">=": "<"}[op]
+		}
+		return fmt.Sprintf("%s %s %s", t.path, op, formatFloat(num))
+	case opRegex:
+		if t.negate {
+			return fmt.Sprintf("%s !~ %s", t.path, strconv.Quote(t.arg))
+		}
+		return fmt.Sprintf("%s =~ %s", t.path, strconv.Quote(t.arg))
+	}
+	if t.negate {
+		return "!" + s
+	}
+	return s
+}
+
+func formatScalar(s log.Scalar) string {
+	v := valueOf(s)
+	switch v.Kind() {
+	case KindString:
+		str, _ := v.AsString()
+		return strconv.Quote(str)
+	case KindFloat:
+		f, _ := v.AsFloat()
+		out := formatFloat(f)
+		if !strings.ContainsAny(out, ".eEIN") {
+			out += ".0"
+		}
+		return out
+	case KindBytes:
+		b, _ := v.AsBytes()
+		return strconv.Quote(string(b))
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
 }
 
 // Selector groups predicates into AND/OR/NONE sets.
@@ -78,21 +278,138 @@ type Selector struct {
 	None []Predicate
 }
 
-func (s Selector) toMapper() mapper.Selector {
+// String returns s in selector syntax (see ParseExpr).
+func (s Selector) String() string {
+	var parts []string
+	if len(s.Any) > 0 {
+		anys := make([]string, len(s.Any))
+		for i, p := range s.Any {
+			anys[i] = p.String()
+		}
+		if len(anys) == 1 {
+			parts = append(parts, anys[0])
+		} else {
+			parts = append(parts, "("+strings.Join(anys, " || ")+")")
+		}
+	}
+	for _, p := range s.All {
+		parts = append(parts, p.String())
+	}
+	for _, p := range s.None {
+		parts = append(parts, "!("+p.String()+")")
+	}
+	if len(parts) == 0 {
+		return "true"
+	}
+	return strings.Join(parts, " && ")
+}
+
+// toMapper converts s into host selectors.
+func (s Selector) toMapper() []mapper.Selector {
+	clauses := s.clauses()
+	out := make([]mapper.Selector, len(clauses))
+	for i, c := range clauses {
+		out[i] = c.toMapper()
+	}
+	return out
+}
+
+// clauses flattens s into host selectors. A selector built only from simple
+// predicates yields exactly one; compound predicates in Any or None are
+// expanded into several selectors whose union matches the same logs.
+func (s Selector) clauses() []clause {
+	base := clause{}
+	var groups [][][]term // each group is an OR of conjunctions
+	for _, p := range s.All {
+		base.add(p.terms...)
+	}
+	for _, p := range s.None {
+		if p.simple() {
+			base.none = append(base.none, p.terms[0])
+			continue
+		}
+		// NOT (a AND b) == (NOT a) OR (NOT b)
+		group := make([][]term, len(p.terms))
+		for i, t := range p.terms {
+			group[i] = []term{not(t)}
+		}
+		groups = append(groups, group)
+	}
+	if anySimple(s.Any) {
+		for _, p := range s.Any {
+			base.any = append(base.any, p.terms[0])
+		}
+	} else {
+		group := make([][]term, len(s.Any))
+		for i, p := range s.Any {
+			group[i] = p.terms
+		}
+		groups = append(groups, group)
+	}
+
+	clauses := []clause{base}
+	for _, group := range groups {
+		next := make([]clause, 0, len(clauses)*len(group))
+		for _, c := range clauses {
+			for _, conj := range group {
+				nc := c.clone()
+				nc.add(conj...)
+				next = append(next, nc)
+			}
+		}
+		clauses = next
+	}
+	return clauses
+}
+
+func anySimple(preds []Predicate) bool {
+	for _, p := range preds {
+		if !p.simple() {
+			return false
+		}
+	}
+	return true
+}
+
+// clause is one host selector under construction. Negated terms are stored
+// un-negated in none.
+type clause struct {
+	any, all, none []term
+}
+
+func (c *clause) add(terms ...term) {
+	for _, t := range terms {
+		if t.negate {
+			c.none = append(c.none, not(t))
+		} else {
+			c.all = append(c.all, t)
+		}
+	}
+}
+
+func (c clause) toMapper() mapper.Selector {
 	return mapper.Selector{
-		Any:  toPredList(s.Any),
-		All:  toPredList(s.All),
-		None: toPredList(s.None),
+		Any:  toPredList(c.any),
+		All:  toPredList(c.all),
+		None: toPredList(c.none),
+	}
+}
+
+func (c clause) clone() clause {
+	return clause{
+		any:  append([]term(nil), c.any...),
+		all:  append([]term(nil), c.all...),
+		none: append([]term(nil), c.none...),
 	}
 }
 
-func toPredList(preds []Predicate) cm.List[mapper.Pred] {
-	if len(preds) == 0 {
+func toPredList(terms []term) cm.List[mapper.Pred] {
+	if len(terms) == 0 {
 		return cm.ToList([]mapper.Pred{})
 	}
-	out := make([]mapper.Pred, len(preds))
-	for i := range preds {
-		out[i] = preds[i].inner
+	out := make([]mapper.Pred, len(terms))
+	for i := range terms {
+		out[i] = terms[i].toMapper()
 	}
 	return cm.ToList(out)
 }
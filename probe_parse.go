@@ -0,0 +1,574 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/telophasehq/tangent-sdk-go/config"
+	"github.com/telophasehq/tangent-sdk-go/internal/logpath"
+)
+
+// SyntaxError reports a malformed selector expression.
+type SyntaxError struct {
+	Line int // 1-based line of the problem
+	Col  int // 1-based column, in characters
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("selector:%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// ParseExpr parses a selector expression such as
+//
+//	has(detail.id) && source == "aws.guardduty" && !(region =~ "^cn-")
+//
+// Comparisons put a field path on the left and a literal on the right:
+//
+//	path == "s" | 42 | 1.5 | true    (and !=)
+//	path > 7                         (and >=, <, <=)
+//	path =~ "regexp"                 (and !~)
+//	path in ["a", "b"]               (strings, ints, floats or bools)
+//
+// Functions are has(path), prefix(path, "s"), regex(path, "re") and
+// between(path, lo, hi). Terms combine with &&, || and !, and parentheses
+// group. Strings use Go syntax, including `raw` strings. Paths use the host's
+// syntax, e.g. detail.findings[0].id or tags["k8s.io/name"].
+func ParseExpr(src string) (Expr, error) {
+	p := &selParser{src: src}
+	p.space()
+	if p.eof() {
+		return nil, p.errorf(p.pos, "empty selector")
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if !p.eof() {
+		return nil, p.errorf(p.pos, "unexpected %s", p.describe())
+	}
+	return e, nil
+}
+
+// ParseSelectors parses a selector expression (see ParseExpr) and flattens
+// it with Selectors.
+func ParseSelectors(src string) ([]Selector, error) {
+	e, err := ParseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	return Selectors(e), nil
+}
+
+// MustParseSelectors is like ParseSelectors but panics on error.
+func MustParseSelectors(src string) []Selector {
+	sels, err := ParseSelectors(src)
+	if err != nil {
+		panic(err)
+	}
+	return sels
+}
+
+// SelectorsFromConfig parses the selector expression stored in the plugin
+// config under key, returning fallback if the key is unset. Call it at load
+// time so operators can change routing without recompiling:
+//
+//	selectors, err := tangent_sdk.SelectorsFromConfig("selector", defaults)
+func SelectorsFromConfig(key string, fallback []Selector) ([]Selector, error) {
+	src, ok := config.Get(key)
+	if !ok {
+		return fallback, nil
+	}
+	sels, err := ParseSelectors(src)
+	if err != nil {
+		return nil, fmt.Errorf("config %q: %w", key, err)
+	}
+	return sels, nil
+}
+
+// selParser is a recursive-descent parser for selector expressions. Each
+// production returns the first *SyntaxError it meets; the parser never
+// panics, so it is safe under runtimes without recover.
+type selParser struct {
+	src string
+	pos int
+}
+
+// errorf returns a *SyntaxError positioned at byte offset off.
+func (p *selParser) errorf(off int, format string, args ...any) error {
+	line, col := 1, 1
+	for _, r := range p.src[:off] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *selParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *selParser) space() {
+	for !p.eof() && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// describe names the input at the current position for error messages.
+func (p *selParser) describe() string {
+	if p.eof() {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return strconv.QuoteRune(r)
+}
+
+// accept consumes tok if it is next.
+func (p *selParser) accept(tok string) bool {
+	p.space()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *selParser) expect(tok string) error {
+	if !p.accept(tok) {
+		return p.errorf(p.pos, "expected %q, found %s", tok, p.describe())
+	}
+	return nil
+}
+
+func (p *selParser) or() (Expr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{e}
+	for p.accept("||") {
+		if e, err = p.and(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return Or(exprs...), nil
+}
+
+func (p *selParser) and() (Expr, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{e}
+	for p.accept("&&") {
+		if e, err = p.unary(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return And(exprs...), nil
+}
+
+func (p *selParser) unary() (Expr, error) {
+	p.space()
+	if strings.HasPrefix(p.src[p.pos:], "!") && !strings.HasPrefix(p.src[p.pos:], "!=") && !strings.HasPrefix(p.src[p.pos:], "!~") {
+		p.pos++
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(e), nil
+	}
+	return p.primary()
+}
+
+func (p *selParser) primary() (Expr, error) {
+	if p.accept("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	p.space()
+	start := p.pos
+	if word := p.word(); word != "" {
+		p.space()
+		if !p.eof() && p.src[p.pos] == '(' {
+			return p.call(start, word)
+		}
+	}
+	p.pos = start
+	return p.comparison()
+}
+
+// word scans an identifier, for recognising function names.
+func (p *selParser) word() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+		if c != '_' && !isLetter(c) && !(p.pos > start && isDigit(c)) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *selParser) call(start int, name string) (Expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var e Expr
+	switch name {
+	case "has":
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		e = Has(path)
+	case "prefix":
+		path, err := p.pathArg()
+		if err != nil {
+			return nil, err
+		}
+		s, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		e = Prefix(path, s)
+	case "regex":
+		path, err := p.pathArg()
+		if err != nil {
+			return nil, err
+		}
+		re, err := p.regex()
+		if err != nil {
+			return nil, err
+		}
+		e = Regex(path, re)
+	case "between":
+		path, err := p.pathArg()
+		if err != nil {
+			return nil, err
+		}
+		lo, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		hi, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		e = Between(path, lo, hi)
+	default:
+		return nil, p.errorf(start, "unknown function %q", name)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// pathArg parses a path followed by the comma before the next argument.
+func (p *selParser) pathArg() (string, error) {
+	path, err := p.path()
+	if err != nil {
+		return "", err
+	}
+	return path, p.expect(",")
+}
+
+func (p *selParser) comparison() (Expr, error) {
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	opPos := p.pos
+	switch {
+	case p.accept("=="):
+		return p.eq(path)
+	case p.accept("!="):
+		e, err := p.eq(path)
+		if err != nil {
+			return nil, err
+		}
+		return Not(e), nil
+	case p.accept("=~"):
+		re, err := p.regex()
+		if err != nil {
+			return nil, err
+		}
+		return Regex(path, re), nil
+	case p.accept("!~"):
+		re, err := p.regex()
+		if err != nil {
+			return nil, err
+		}
+		return Not(Regex(path, re)), nil
+	case p.accept(">="):
+		return p.bound(path, Gte)
+	case p.accept("<="):
+		return p.bound(path, Lte)
+	case p.accept(">"):
+		return p.bound(path, Gt)
+	case p.accept("<"):
+		return p.bound(path, Lt)
+	case p.keyword("in"):
+		return p.in(path)
+	}
+	return nil, p.errorf(opPos, "expected comparison operator after %s, found %s", path, p.describe())
+}
+
+// bound parses the number on the right of an ordering comparison.
+func (p *selParser) bound(path string, op func(string, float64) Predicate) (Expr, error) {
+	n, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+	return op(path, n), nil
+}
+
+// keyword consumes word if it is next and not followed by a path character.
+func (p *selParser) keyword(word string) bool {
+	p.space()
+	end := p.pos + len(word)
+	if !strings.HasPrefix(p.src[p.pos:], word) || end < len(p.src) && isPathChar(p.src[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *selParser) path() (string, error) {
+	p.space()
+	start := p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+		if isPathChar(c) {
+			p.pos++
+			continue
+		}
+		if c != '[' {
+			break
+		}
+		p.pos++
+		if !p.eof() && p.src[p.pos] == '"' {
+			q, err := strconv.QuotedPrefix(p.src[p.pos:])
+			if err != nil {
+				return "", p.errorf(p.pos, "unterminated string in path")
+			}
+			p.pos += len(q)
+		} else {
+			for !p.eof() && isDigit(p.src[p.pos]) {
+				p.pos++
+			}
+		}
+		if p.eof() || p.src[p.pos] != ']' {
+			return "", p.errorf(p.pos, "expected ']' in path, found %s", p.describe())
+		}
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf(start, "expected field path, found %s", p.describe())
+	}
+	path := p.src[start:p.pos]
+	if _, err := logpath.Parse(path); err != nil {
+		var perr *logpath.Error
+		if errors.As(err, &perr) {
+			return "", p.errorf(start+perr.Offset, "invalid path %s: %s", path, perr.Msg)
+		}
+		return "", p.errorf(start, "invalid path %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// literal is a parsed string, number or bool.
+type literal struct {
+	pos   int
+	value any // string, int64, float64 or bool
+}
+
+func (p *selParser) literal() (literal, error) {
+	p.space()
+	lit := literal{pos: p.pos}
+	switch {
+	case p.eof():
+		return lit, p.errorf(p.pos, "expected value, found end of input")
+	case p.src[p.pos] == '"' || p.src[p.pos] == '`':
+		q, err := strconv.QuotedPrefix(p.src[p.pos:])
+		if err != nil {
+			return lit, p.errorf(p.pos, "malformed string")
+		}
+		s, _ := strconv.Unquote(q)
+		p.pos += len(q)
+		lit.value = s
+	case p.keyword("true"):
+		lit.value = true
+	case p.keyword("false"):
+		lit.value = false
+	case p.src[p.pos] == '-' || p.src[p.pos] == '+' || p.src[p.pos] == '.' || isDigit(p.src[p.pos]):
+		start := p.pos
+		p.pos++
+		for !p.eof() && (isDigit(p.src[p.pos]) || isLetter(p.src[p.pos]) || strings.IndexByte("._", p.src[p.pos]) >= 0 ||
+			(p.src[p.pos] == '-' || p.src[p.pos] == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			lit.value = i
+		} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+			lit.value = f
+		} else {
+			return lit, p.errorf(start, "malformed number %s", text)
+		}
+	default:
+		return lit, p.errorf(p.pos, "expected value, found %s", p.describe())
+	}
+	return lit, nil
+}
+
+func (p *selParser) str() (string, error) {
+	lit, err := p.literal()
+	if err != nil {
+		return "", err
+	}
+	s, ok := lit.value.(string)
+	if !ok {
+		return "", p.errorf(lit.pos, "expected string")
+	}
+	return s, nil
+}
+
+func (p *selParser) regex() (string, error) {
+	lit, err := p.literal()
+	if err != nil {
+		return "", err
+	}
+	s, ok := lit.value.(string)
+	if !ok {
+		return "", p.errorf(lit.pos, "expected regular expression string")
+	}
+	if _, err := regexp.Compile(s); err != nil {
+		return "", p.errorf(lit.pos, "%v", err)
+	}
+	return s, nil
+}
+
+func (p *selParser) number() (float64, error) {
+	lit, err := p.literal()
+	if err != nil {
+		return 0, err
+	}
+	switch v := lit.value.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	}
+	return 0, p.errorf(lit.pos, "expected number")
+}
+
+func (p *selParser) eq(path string) (Expr, error) {
+	lit, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	switch v := lit.value.(type) {
+	case string:
+		return EqString(path, v), nil
+	case int64:
+		return EqInt(path, v), nil
+	case float64:
+		return EqFloat(path, v), nil
+	default:
+		return EqBool(path, v.(bool)), nil
+	}
+}
+
+func (p *selParser) in(path string) (Expr, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var lits []literal
+	if !p.accept("]") {
+		for {
+			lit, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			lits = append(lits, lit)
+			if p.accept("]") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(lits) == 0 {
+		return InStrings(path), nil
+	}
+	switch lits[0].value.(type) {
+	case string:
+		vs, err := inValues[string](p, lits, "string")
+		return InStrings(path, vs...), err
+	case int64:
+		vs, err := inValues[int64](p, lits, "int")
+		return InInts(path, vs...), err
+	case float64:
+		vs, err := inValues[float64](p, lits, "float")
+		return InFloats(path, vs...), err
+	default:
+		vs, err := inValues[bool](p, lits, "bool")
+		return InBools(path, vs...), err
+	}
+}
+
+// inValues converts an in-list whose first element has type V, failing at
+// the first element of another type.
+func inValues[V any](p *selParser, lits []literal, kind string) ([]V, error) {
+	out := make([]V, len(lits))
+	for i, lit := range lits {
+		v, ok := lit.value.(V)
+		if !ok {
+			return nil, p.errorf(lit.pos, "in list mixes %s and %T values", kind, lit.value)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isPathChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || strings.IndexByte("_-.@$/:", c) >= 0 || c >= utf8.RuneSelf
+}
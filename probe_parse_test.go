@@ -0,0 +1,82 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		src       string
+		line, col int
+		msg       string
+	}{
+		{"", 1, 1, "empty selector"},
+		{"   ", 1, 4, "empty selector"},
+		{"a ==", 1, 5, "expected value, found end of input"},
+		{"a == 1 &&\n  b ~ 2", 2, 5, "expected comparison operator after b, found '~'"},
+		{"foo(a)", 1, 1, `unknown function "foo"`},
+		{"(a == 1", 1, 8, `expected ")", found end of input`},
+		{"a == 1)", 1, 7, "unexpected ')'"},
+		{`a in [1, "x"]`, 1, 10, "in list mixes int and string values"},
+		{`a == "x`, 1, 6, "malformed string"},
+		{`a > "x"`, 1, 5, "expected number"},
+		{"between(a, 1)", 1, 13, `expected ",", found ')'`},
+		{`a =~ "["`, 1, 6, "error parsing regexp: missing closing ]: `[`"},
+		{"prefix(a, 1)", 1, 11, "expected string"},
+		{"a[x] == 1", 1, 3, "expected ']' in path, found 'x'"},
+		// Columns count characters, not bytes.
+		{`x == "é" )`, 1, 10, "unexpected ')'"},
+		{"a == 1 é", 1, 8, "unexpected 'é'"},
+		{"a\n\n   == true ||\n\t!", 4, 3, "expected field path, found end of input"},
+		// Errors deep in a production propagate unchanged.
+		{"a == 1 || (b == 2 && !(c in [1,", 1, 32, "expected value, found end of input"},
+		{"has(a", 1, 6, `expected ")", found end of input`},
+		{"a != [", 1, 6, "expected value, found '['"},
+		{"a !~ 1", 1, 6, "expected regular expression string"},
+		{`between(a, 1, "x")`, 1, 15, "expected number"},
+		{`a in ["x", true]`, 1, 12, "in list mixes string and bool values"},
+	}
+	for _, tt := range tests {
+		_, err := ParseExpr(tt.src)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("ParseExpr(%q) = %v, want a *SyntaxError", tt.src, err)
+			continue
+		}
+		if se.Line != tt.line || se.Col != tt.col || se.Msg != tt.msg {
+			t.Errorf("ParseExpr(%q) = %d:%d %q, want %d:%d %q", tt.src, se.Line, se.Col, se.Msg, tt.line, tt.col, tt.msg)
+		}
+	}
+}
+
+func TestParseExprString(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{`has(a) && b == "x"`, `has(a) && b == "x"`},
+		{`!(a == 1) || a == 2`, `!(a == 1) || a == 2`},
+		{"a >= 0.5 && a < 1", "a >= 0.5 && a > -Inf && !(a >= 1)"},
+		{`a in ["x", "y"] && !prefix(b, "p")`, `a in ["x", "y"] && !(prefix(b, "p"))`},
+		{"between(a, 1, 2)", "a >= 1 && !(a > 2)"},
+		{"a =~ `^\\d+$`", `a =~ "^\\d+$"`},
+		{`tags["k8s.io/name"] != true`, `!(tags["k8s.io/name"] == true)`},
+	}
+	for _, tt := range tests {
+		sels, err := ParseSelectors(tt.src)
+		if err != nil {
+			t.Errorf("ParseSelectors(%q): %v", tt.src, err)
+			continue
+		}
+		got := ""
+		for i, s := range sels {
+			if i > 0 {
+				got += " || "
+			}
+			got += s.String()
+		}
+		if got != tt.want {
+			t.Errorf("ParseSelectors(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRangePredicateString(t *testing.T) {
+	for _, v := range []float64{0, 1, -1, 0.1, 0.3, 443, 1e300, -1e300, 5e-324, math.MaxFloat64} {
+		num := formatFloat(v)
+		tests := []struct {
+			got, want string
+		}{
+			{Gt("x", v).String(), "x > " + num},
+			{Gte("x", v).String(), "x >= " + num},
+			{Lt("x", v).String(), "x < " + num},
+			{Lte("x", v).String(), "x <= " + num},
+			// Single terms, as Explain and Selectors print them.
+			{gte("x", v).String(), "x >= " + num},
+			{not(gte("x", v)).String(), "x < " + num},
+			{not(gt("x", v)).String(), "x <= " + num},
+		}
+		for _, tt := range tests {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		}
+	}
+}
+
+func TestValidateEmptyPredicate(t *testing.T) {
+	for _, s := range []Selector{
+		{None: []Predicate{{}}},
+		{Any: []Predicate{{}, Has("a")}},
+		{All: []Predicate{{}}},
+	} {
+		err := Validate([]Selector{s})
+		if !errors.Is(err, errEmptyPredicate) {
+			t.Errorf("Validate(%s) = %v, want empty predicate error", s, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "empty predicate") {
+			t.Errorf("message %q does not explain the problem", err)
+		}
+	}
+}
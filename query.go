@@ -0,0 +1,338 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+)
+
+// Query is a compiled path query that can match many values. In addition
+// to ordinary path steps it supports
+//
+//	a.b[*].c                  every element of a list, or member of an object (also a.*)
+//	..ip                      ip at any depth (also ..*, ..[0])
+//	findings[?severity > 7]   elements for which a selector expression holds
+//
+// Filters use selector syntax (see ParseExpr) with paths relative to the
+// element. A leading "$" is accepted and ignored.
+type Query struct {
+	src   string
+	steps []queryStep
+}
+
+type stepKind uint8
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepWild
+	stepFilter
+)
+
+type queryStep struct {
+	kind      stepKind
+	recursive bool // preceded by ".."
+	key       string
+	index     int
+	filter    Expr
+}
+
+// QueryResult is a scalar found by a Query and the concrete path it was
+// found at, which can be passed back to the Log accessors.
+type QueryResult struct {
+	Path  string
+	Value Value
+}
+
+// QueryError reports a malformed query.
+type QueryError struct {
+	Query  string
+	Offset int // byte offset of the problem
+	Msg    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query %q at offset %d: %s", e.Query, e.Offset, e.Msg)
+}
+
+// ParseQuery compiles a query.
+func ParseQuery(s string) (*Query, error) {
+	q := &Query{src: s}
+	fail := func(off int, format string, args ...any) (*Query, error) {
+		return nil, &QueryError{Query: s, Offset: off, Msg: fmt.Sprintf(format, args...)}
+	}
+	i := 0
+	if strings.HasPrefix(s, "$") {
+		i++
+	}
+	for first := true; i < len(s); first = false {
+		var step queryStep
+		switch {
+		case strings.HasPrefix(s[i:], ".."):
+			step.recursive = true
+			i += 2
+			if i < len(s) && s[i] == '.' {
+				return fail(i, "unexpected '.'")
+			}
+		case s[i] == '.':
+			i++
+		case s[i] != '[' && !first:
+			return fail(i, "expected '.' or '['")
+		}
+		if i >= len(s) {
+			return fail(i, "expected key, '*' or '['")
+		}
+
+		switch c := s[i]; {
+		case c == '*':
+			step.kind = stepWild
+			i++
+		case c == '[':
+			end, err := q.bracket(s, i, &step)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case isQueryKeyChar(c):
+			j := i
+			for j < len(s) && isQueryKeyChar(s[j]) {
+				j++
+			}
+			step.kind, step.key = stepKey, s[i:j]
+			i = j
+		default:
+			return fail(i, "unexpected %q", c)
+		}
+		q.steps = append(q.steps, step)
+	}
+	return q, nil
+}
+
+// bracket parses the bracketed step starting at s[i] into step and returns
+// the offset just past its ']'.
+func (q *Query) bracket(s string, i int, step *queryStep) (int, error) {
+	fail := func(off int, format string, args ...any) (int, error) {
+		return 0, &QueryError{Query: s, Offset: off, Msg: fmt.Sprintf(format, args...)}
+	}
+	j := i + 1
+	switch {
+	case strings.HasPrefix(s[j:], "*]"):
+		step.kind = stepWild
+		return j + 2, nil
+	case strings.HasPrefix(s[j:], `"`):
+		lit, err := strconv.QuotedPrefix(s[j:])
+		if err != nil {
+			return fail(j, "unterminated quoted key")
+		}
+		key, _ := strconv.Unquote(lit)
+		j += len(lit)
+		if j >= len(s) || s[j] != ']' {
+			return fail(j, "expected ']'")
+		}
+		step.kind, step.key = stepKey, key
+		return j + 1, nil
+	case strings.HasPrefix(s[j:], "?"):
+		j++
+		end, err := filterEnd(s, j)
+		if err != nil {
+			return fail(j, "%v", err)
+		}
+		e, err := ParseExpr(s[j:end])
+		if err != nil {
+			var se *SyntaxError
+			if errors.As(err, &se) && se.Line == 1 {
+				return fail(j+se.Col-1, "filter: %s", se.Msg)
+			}
+			return fail(j, "filter: %v", err)
+		}
+		step.kind, step.filter = stepFilter, e
+		return end + 1, nil
+	}
+	end := strings.IndexByte(s[j:], ']')
+	if end < 0 {
+		return fail(i, "unterminated '['")
+	}
+	n, err := strconv.Atoi(s[j : j+end])
+	if err != nil || n < 0 {
+		return fail(j, "expected index, '*', quoted key or '?' filter")
+	}
+	step.kind, step.index = stepIndex, n
+	return j + end + 1, nil
+}
+
+// filterEnd returns the offset of the ']' closing a filter that starts at
+// s[i], skipping brackets and strings inside it.
+func filterEnd(s string, i int) (int, error) {
+	depth := 0
+	for i < len(s) {
+		switch s[i] {
+		case '"', '`':
+			lit, err := strconv.QuotedPrefix(s[i:])
+			if err != nil {
+				return 0, errors.New("unterminated string in filter")
+			}
+			i += len(lit)
+			continue
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		}
+		i++
+	}
+	return 0, errors.New("unterminated filter")
+}
+
+func isQueryKeyChar(c byte) bool {
+	return c != '.' && isPathChar(c)
+}
+
+// MustQuery is like ParseQuery but panics if s is malformed. It is meant for
+// package-level query variables.
+func MustQuery(s string) *Query {
+	q, err := ParseQuery(s)
+	if err != nil {
+		panic("tangent_sdk: " + err.Error())
+	}
+	return q
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// Find returns every scalar in l matched by q, each once. Object members are
+// visited in sorted key order. Matched objects and lists are skipped; end the
+// query with [*] to collect the elements of a list.
+//
+// A query with wildcards, filters or recursive descent parses l.Log() once
+// and walks the parsed document in the plugin, rather than asking the host
+// for the keys, length and contents of every node it visits.
+//
+//	var ips = tangent_sdk.MustQuery("..ip")
+//
+//	for _, r := range ips.Find(l) {
+//		ip, _ := r.Value.AsString()
+//		out.IPs = append(out.IPs, ip)
+//	}
+func (q *Query) Find(l Log) []QueryResult {
+	if q.descends() {
+		l = parsed(l)
+	}
+	nodes := []string{""}
+	for _, step := range q.steps {
+		var next []string
+		seen := map[string]bool{}
+		add := func(p string) {
+			if !seen[p] {
+				seen[p] = true
+				next = append(next, p)
+			}
+		}
+		for _, n := range nodes {
+			if step.recursive {
+				walk(l, n, func(p string) { step.apply(l, p, add) })
+			} else {
+				step.apply(l, n, add)
+			}
+		}
+		nodes = next
+	}
+
+	var out []QueryResult
+	for _, p := range nodes {
+		if v, ok := l.Get(p); ok {
+			out = append(out, QueryResult{Path: p, Value: v})
+		}
+	}
+	return out
+}
+
+// Query compiles q and runs it against v. Prefer MustQuery for queries run
+// on every log.
+func (v Log) Query(q string) ([]QueryResult, error) {
+	cq, err := ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	return cq.Find(v), nil
+}
+
+// descends reports whether q visits the children of a node, as opposed to
+// following a single path.
+func (q *Query) descends() bool {
+	for _, s := range q.steps {
+		if s.recursive || s.kind == stepWild || s.kind == stepFilter {
+			return true
+		}
+	}
+	return false
+}
+
+// parsed returns l backed by an in-process copy of its document, keeping any
+// Sub prefix. l is returned unchanged if it is already in-process or its
+// document does not parse.
+func parsed(l Log) Log {
+	src, prefix := l.logview, ""
+	if sub, ok := src.(*subSource); ok {
+		src, prefix = sub.src, sub.prefix
+	}
+	if _, ok := src.(*jsonlog.View); ok {
+		return l
+	}
+	view, err := jsonlog.Parse([]byte(src.Log()))
+	if err != nil {
+		return l
+	}
+	return newLog(view).Sub(prefix)
+}
+
+// apply passes the paths step selects under p to add.
+func (s queryStep) apply(l Log, p string, add func(string)) {
+	switch s.kind {
+	case stepKey:
+		if c := fieldPath(p, s.key); l.Has(c) {
+			add(c)
+		}
+	case stepIndex:
+		if c := indexPath(p, s.index); l.Has(c) {
+			add(c)
+		}
+	case stepWild:
+		children(l, p, add)
+	case stepFilter:
+		children(l, p, func(c string) {
+			if s.filter.matches(l.Sub(c)) {
+				add(c)
+			}
+		})
+	}
+}
+
+// children passes the members of the object, or elements of the list, at p
+// to fn.
+func children(l Log, p string, fn func(string)) {
+	if keys := l.Keys(p); len(keys) > 0 {
+		for _, k := range keys {
+			fn(fieldPath(p, k))
+		}
+		return
+	}
+	if n := l.Len(p); n != nil {
+		for i := 0; i < int(*n); i++ {
+			fn(indexPath(p, i))
+		}
+	}
+}
+
+// walk passes p and everything below it to fn, parents first.
+func walk(l Log, p string, fn func(string)) {
+	fn(p)
+	children(l, p, func(c string) { walk(l, c, fn) })
+}
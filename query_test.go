@@ -0,0 +1,177 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+	"github.com/telophasehq/tangent-sdk-go/internal/logsource"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"go.bytecodealliance.org/cm"
+)
+
+const queryTestDoc = `{
+	"src": {"ip": "10.0.0.1", "port": 443},
+	"dst": {"ip": "10.0.0.2"},
+	"findings": [
+		{"id": "a", "severity": 8, "tags": ["x", "y"]},
+		{"id": "b", "severity": 3},
+		{"id": "c", "severity": 9, "nested": {"ip": "10.0.0.3"}}
+	],
+	"a.b": true
+}`
+
+func TestFind(t *testing.T) {
+	l := testLogs(t, queryTestDoc)[0]
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"src.ip", `src.ip="10.0.0.1"`},
+		{"$.src.port", `src.port=443`},
+		{"src.*", `src.ip="10.0.0.1" src.port=443`},
+		{"findings[*].id", `findings[0].id="a" findings[1].id="b" findings[2].id="c"`},
+		{"findings[1].id", `findings[1].id="b"`},
+		{"findings[*]", ``},
+		{"findings[0].tags[*]", `findings[0].tags[0]="x" findings[0].tags[1]="y"`},
+		{"..ip", `dst.ip="10.0.0.2" findings[2].nested.ip="10.0.0.3" src.ip="10.0.0.1"`},
+		{"findings[?severity > 7].id", `findings[0].id="a" findings[2].id="c"`},
+		{`findings[?has(tags) || id == "b"].id`, `findings[0].id="a" findings[1].id="b"`},
+		{`["a.b"]`, `["a.b"]=true`},
+		{"missing..ip", ``},
+		{"findings[7].id", ``},
+	}
+	for _, tt := range tests {
+		rs, err := l.Query(tt.query)
+		if err != nil {
+			t.Errorf("Query(%q): %v", tt.query, err)
+			continue
+		}
+		got := make([]string, len(rs))
+		for i, r := range rs {
+			got[i] = fmt.Sprintf("%s=%s", r.Path, actual(l, Has(r.Path)))
+			if v, ok := l.Get(r.Path); !ok || !scalarEqual(v, r.Value) {
+				t.Errorf("Query(%q): Get(%q) does not return the result's value", tt.query, r.Path)
+			}
+		}
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("Query(%q) = %s, want %s", tt.query, s, tt.want)
+		}
+	}
+}
+
+// hostCalls stands in for the host's logview and counts the calls made
+// to it.
+type hostCalls struct {
+	logsource.Source
+	logCalls, otherCalls int
+}
+
+func (c *hostCalls) Get(path string) cm.Option[log.Scalar] {
+	c.otherCalls++
+	return c.Source.Get(path)
+}
+
+func (c *hostCalls) Has(path string) bool {
+	c.otherCalls++
+	return c.Source.Has(path)
+}
+
+func (c *hostCalls) Keys(path string) cm.List[string] {
+	c.otherCalls++
+	return c.Source.Keys(path)
+}
+
+func (c *hostCalls) Len(path string) cm.Option[uint32] {
+	c.otherCalls++
+	return c.Source.Len(path)
+}
+
+func (c *hostCalls) Log() string {
+	c.logCalls++
+	return c.Source.Log()
+}
+
+func TestFindParsesOnce(t *testing.T) {
+	view, err := jsonlog.Parse([]byte(queryTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query  string
+		sub    string
+		want   string
+		parsed bool
+	}{
+		{"..ip", "", "dst.ip findings[2].nested.ip src.ip", true},
+		{"findings[?severity > 7].id", "", "findings[0].id findings[2].id", true},
+		{"[*].id", "findings", "[0].id [1].id [2].id", true},
+		{"..ip", "findings[2]", "nested.ip", true},
+		{"src.ip", "", "src.ip", false},
+		{"ip", "dst", "ip", false},
+	}
+	for _, tt := range tests {
+		src := &hostCalls{Source: view}
+		l := newLog(src).Sub(tt.sub)
+		rs, err := l.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range rs {
+			got = append(got, r.Path)
+		}
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("Sub(%q).Query(%q) = %s, want %s", tt.sub, tt.query, s, tt.want)
+		}
+		if tt.parsed && (src.logCalls != 1 || src.otherCalls != 0) {
+			t.Errorf("Sub(%q).Query(%q) made %d Log and %d other host calls, want 1 and 0",
+				tt.sub, tt.query, src.logCalls, src.otherCalls)
+		}
+		if !tt.parsed && src.logCalls != 0 {
+			t.Errorf("Sub(%q).Query(%q) parsed the log for a single path", tt.sub, tt.query)
+		}
+	}
+
+	// A document that does not parse is walked through the host.
+	bad := &hostCalls{Source: &unparsable{view}}
+	rs, _ := newLog(bad).Query("..ip")
+	if len(rs) != 3 || bad.otherCalls == 0 {
+		t.Errorf("Query on an unparsable log = %d results, %d host calls", len(rs), bad.otherCalls)
+	}
+}
+
+type unparsable struct{ logsource.Source }
+
+func (unparsable) Log() string { return "{" }
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+		msg    string
+	}{
+		{"a.", 2, "expected key, '*' or '['"},
+		{"a...b", 3, "unexpected '.'"},
+		{"a[", 1, "unterminated '['"},
+		{"a[x]", 2, "expected index, '*', quoted key or '?' filter"},
+		{`a["b]`, 2, "unterminated quoted key"},
+		{`a["b"x]`, 5, "expected ']'"},
+		{"a[?b > ]", 7, "filter: expected value, found end of input"},
+		{"a[?b == 1", 3, "unterminated filter"},
+		{"a b", 1, "expected '.' or '['"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Errorf("ParseQuery(%q) = %v, want a *QueryError", tt.query, err)
+			continue
+		}
+		if qe.Offset != tt.offset || qe.Msg != tt.msg {
+			t.Errorf("ParseQuery(%q) = %d %q, want %d %q", tt.query, qe.Offset, qe.Msg, tt.offset, tt.msg)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tangent_sdk
+
+import "errors"
+
+// ErrNoRoute is reported for a log that matches none of a plugin's routes.
+var ErrNoRoute = errors.New("tangent: no route matches log")
+
+// Binding pairs a selector expression with a typed handler. Create one with
+// Route or RouteMulti and register a set of them with WireRoutes.
+type Binding struct {
+	selector Expr
+	handle   func(Log) ([]any, error)
+}
+
+// Route sends logs matching selector to handler. selector may be a Selector,
+// a Predicate or any And/Or/Not expression.
+func Route[T any](selector Expr, handler ProcessLog[T]) Binding {
+	return Binding{selector: selector, handle: func(l Log) ([]any, error) {
+		out, err := handler(l)
+		if err != nil {
+			return nil, err
+		}
+		return []any{out}, nil
+	}}
+}
+
+// RouteMulti is like Route for a handler that emits zero or more outputs per
+// log.
+func RouteMulti[T any](selector Expr, handler ProcessLogMulti[T]) Binding {
+	return Binding{selector: selector, handle: func(l Log) ([]any, error) {
+		outs, err := handler(l)
+		if err != nil {
+			return nil, err
+		}
+		anys := make([]any, len(outs))
+		for i := range outs {
+			anys[i] = outs[i]
+		}
+		return anys, nil
+	}}
+}
+
+// WireRoutes connects a plugin whose logs go to different typed handlers
+// depending on which selector they match:
+//
+//	tangent_sdk.WireRoutes(meta, []tangent_sdk.Binding{
+//		tangent_sdk.Route(guardDutySelector, handleGuardDuty),
+//		tangent_sdk.Route(cloudTrailSelector, handleCloudTrail),
+//	})
+//
+// The probe advertises every route's selector, but the host only reports
+// that a log matched one of them, not which. Each log is therefore evaluated
+// in the plugin against the routes in order, with the semantics described
+// at Selector.Match, and handled by the first match. A log the host sent
+// that matches no route locally fails with ErrNoRoute, so a disagreement
+// between the two evaluators surfaces as an error (and a dead letter, with
+// WithDeadLetter) rather than being dropped.
+func WireRoutes(meta Metadata, routes []Binding, opts ...Option) {
+	var selectors []Selector
+	for i := range routes {
+		selectors = append(selectors, Selectors(routes[i].selector)...)
+	}
+	wire(meta, selectors, &processor[any]{name: meta.Name, handler: dispatch(routes), opts: newOptions(opts)})
+}
+
+// dispatch returns a handler that passes each log to the first route it
+// matches.
+func dispatch(routes []Binding) ProcessLogMulti[any] {
+	return func(l Log) ([]any, error) {
+		for i := range routes {
+			if routes[i].selector.matches(l) {
+				return routes[i].handle(l)
+			}
+		}
+		return nil, ErrNoRoute
+	}
+}
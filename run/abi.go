@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"github.com/tetratelabs/wazero/api"
+	"go.bytecodealliance.org/cm"
+)
+
+// Canonical ABI layouts (wasm32) of the records crossing the boundary.
+const (
+	scalarSize     = 16 // u8 tag, payload at +8
+	scalarAlign    = 8
+	stringSize     = 8 // ptr, len
+	fieldSize      = 24
+	selectorSize   = 24 // three lists
+	predSize       = 32 // u8 tag, payload at +8
+	requestSize    = 52
+	responseSize   = 40
+	headerPairSize = 16
+)
+
+var (
+	wasmMagic        = []byte("\x00asm")
+	coreVersion      = []byte{0x01, 0x00, 0x00, 0x00}
+	componentVersion = []byte{0x0d, 0x00, 0x01, 0x00}
+)
+
+// guest is the calling module's memory and allocator.
+type guest struct {
+	ctx context.Context
+	mod api.Module
+}
+
+// errTrap aborts the current guest call with a message.
+type errTrap string
+
+func (e errTrap) Error() string { return string(e) }
+
+func (g guest) mem() api.Memory {
+	return g.mod.Memory()
+}
+
+// alloc reserves size bytes in guest memory through cabi_realloc, falling back
+// to malloc for modules built without it.
+func (g guest) alloc(size, align uint32) uint32 {
+	if size == 0 {
+		return align
+	}
+	if fn := g.mod.ExportedFunction("cabi_realloc"); fn != nil {
+		res, err := fn.Call(g.ctx, 0, 0, uint64(align), uint64(size))
+		if err != nil {
+			panic(errTrap("cabi_realloc: " + err.Error()))
+		}
+		return api.DecodeU32(res[0])
+	}
+	if fn := g.mod.ExportedFunction("malloc"); fn != nil {
+		res, err := fn.Call(g.ctx, uint64(size))
+		if err != nil {
+			panic(errTrap("malloc: " + err.Error()))
+		}
+		return api.DecodeU32(res[0])
+	}
+	panic(errTrap("guest exports neither cabi_realloc nor malloc"))
+}
+
+func (g guest) read(ptr, n uint32) []byte {
+	b, ok := g.mem().Read(ptr, n)
+	if !ok {
+		panic(errTrap(fmt.Sprintf("out of bounds read at %d+%d", ptr, n)))
+	}
+	return append([]byte(nil), b...)
+}
+
+func (g guest) readString(ptr, n uint32) string {
+	return string(g.read(ptr, n))
+}
+
+func (g guest) u8(addr uint32) uint8 {
+	v, ok := g.mem().ReadByte(addr)
+	if !ok {
+		panic(errTrap(fmt.Sprintf("out of bounds read at %d", addr)))
+	}
+	return v
+}
+
+func (g guest) u16(addr uint32) uint16 {
+	v, ok := g.mem().ReadUint16Le(addr)
+	if !ok {
+		panic(errTrap(fmt.Sprintf("out of bounds read at %d", addr)))
+	}
+	return v
+}
+
+func (g guest) u32(addr uint32) uint32 {
+	v, ok := g.mem().ReadUint32Le(addr)
+	if !ok {
+		panic(errTrap(fmt.Sprintf("out of bounds read at %d", addr)))
+	}
+	return v
+}
+
+func (g guest) u64(addr uint32) uint64 {
+	v, ok := g.mem().ReadUint64Le(addr)
+	if !ok {
+		panic(errTrap(fmt.Sprintf("out of bounds read at %d", addr)))
+	}
+	return v
+}
+
+// stringAt reads a (ptr, len) string stored at addr.
+func (g guest) stringAt(addr uint32) string {
+	return g.readString(g.u32(addr), g.u32(addr+4))
+}
+
+func (g guest) putU8(addr uint32, v uint8) {
+	if !g.mem().WriteByte(addr, v) {
+		panic(errTrap(fmt.Sprintf("out of bounds write at %d", addr)))
+	}
+}
+
+func (g guest) putU32(addr, v uint32) {
+	if !g.mem().WriteUint32Le(addr, v) {
+		panic(errTrap(fmt.Sprintf("out of bounds write at %d", addr)))
+	}
+}
+
+func (g guest) putU64(addr uint32, v uint64) {
+	if !g.mem().WriteUint64Le(addr, v) {
+		panic(errTrap(fmt.Sprintf("out of bounds write at %d", addr)))
+	}
+}
+
+func (g guest) putBytes(addr uint32, b []byte) {
+	if !g.mem().Write(addr, b) {
+		panic(errTrap(fmt.Sprintf("out of bounds write at %d", addr)))
+	}
+}
+
+// putString copies s into freshly allocated guest memory and stores the
+// resulting (ptr, len) pair at addr.
+func (g guest) putString(addr uint32, s string) {
+	ptr := g.alloc(uint32(len(s)), 1)
+	g.putBytes(ptr, []byte(s))
+	g.putU32(addr, ptr)
+	g.putU32(addr+4, uint32(len(s)))
+}
+
+// putList allocates n elements of size bytes, stores (ptr, n) at addr and
+// returns the element base pointer.
+func (g guest) putList(addr uint32, n int, size, align uint32) uint32 {
+	ptr := g.alloc(uint32(n)*size, align)
+	g.putU32(addr, ptr)
+	g.putU32(addr+4, uint32(n))
+	return ptr
+}
+
+func (g guest) putScalar(addr uint32, s log.Scalar) {
+	g.putU8(addr, s.Tag())
+	switch s.Tag() {
+	case 0:
+		g.putString(addr+8, *s.Str())
+	case 1:
+		g.putU64(addr+8, uint64(*s.Int()))
+	case 2:
+		g.putU64(addr+8, math.Float64bits(*s.Float()))
+	case 3:
+		var b uint8
+		if *s.Boolean() {
+			b = 1
+		}
+		g.putU8(addr+8, b)
+	case 4:
+		data := s.Bytes().Slice()
+		ptr := g.putList(addr+8, len(data), 1, 1)
+		g.putBytes(ptr, data)
+	}
+}
+
+func (g guest) scalarAt(addr uint32) log.Scalar {
+	switch g.u8(addr) {
+	case 0:
+		return log.ScalarStr(g.stringAt(addr + 8))
+	case 1:
+		return log.ScalarInt(int64(g.u64(addr + 8)))
+	case 2:
+		return log.ScalarFloat(math.Float64frombits(g.u64(addr + 8)))
+	case 3:
+		return log.ScalarBoolean(g.u8(addr+8) != 0)
+	default:
+		return log.ScalarBytes(cm.ToList(g.read(g.u32(addr+8), g.u32(addr+12))))
+	}
+}
+
+// liftScalar rebuilds a scalar passed as flattened (tag, i64, i32) params.
+func (g guest) liftScalar(tag uint32, p1 uint64, p2 uint32) log.Scalar {
+	switch tag {
+	case 0:
+		return log.ScalarStr(g.readString(uint32(p1), p2))
+	case 1:
+		return log.ScalarInt(int64(p1))
+	case 2:
+		return log.ScalarFloat(math.Float64frombits(p1))
+	case 3:
+		return log.ScalarBoolean(p1 != 0)
+	default:
+		return log.ScalarBytes(cm.ToList(g.read(uint32(p1), p2)))
+	}
+}
+
+// coreModule returns the core wasm module to instantiate. Components are
+// unwrapped to the core module that exports the mapper interface.
+func coreModule(bin []byte) ([]byte, error) {
+	if len(bin) < 8 || !bytes.Equal(bin[:4], wasmMagic) {
+		return nil, errors.New("not a wasm binary")
+	}
+	switch {
+	case bytes.Equal(bin[4:8], coreVersion):
+		return bin, nil
+	case bytes.Equal(bin[4:8], componentVersion):
+	default:
+		return nil, fmt.Errorf("unsupported wasm version % x", bin[4:8])
+	}
+
+	const coreModuleSection = 0x01
+	var best []byte
+	for off := 8; off < len(bin); {
+		id := bin[off]
+		size, n, err := uleb128(bin[off+1:])
+		if err != nil {
+			return nil, err
+		}
+		start := off + 1 + n
+		end := start + int(size)
+		if end > len(bin) {
+			return nil, errors.New("truncated component section")
+		}
+		if id == coreModuleSection {
+			mod := bin[start:end]
+			if bytes.Contains(mod, []byte("tangent:logs/mapper@")) {
+				return mod, nil
+			}
+			if len(mod) > len(best) {
+				best = mod
+			}
+		}
+		off = end
+	}
+	if best == nil {
+		return nil, errors.New("component contains no core module")
+	}
+	return best, nil
+}
+
+func uleb128(b []byte) (uint64, int, error) {
+	var v uint64
+	var shift uint
+	for i, c := range b {
+		v |= uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, i + 1, nil
+		}
+		shift += 7
+		if shift > 63 {
+			break
+		}
+	}
+	return 0, 0, errors.New("malformed LEB128")
+}
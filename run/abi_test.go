@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"go.bytecodealliance.org/cm"
+)
+
+// Wasm binary encoding, enough to assemble test modules.
+
+func uleb(n uint64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb(n int64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if (n == 0 && c&0x40 == 0) || (n == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	return append(uleb(uint64(len(items))), bytes.Join(items, nil)...)
+}
+
+func wasmName(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+func section(id byte, items ...[]byte) []byte {
+	payload := vec(items...)
+	return append(append([]byte{id}, uleb(uint64(len(payload)))...), payload...)
+}
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+const (
+	i32 = 0x7f
+
+	opEnd       = 0x0b
+	opSelect    = 0x1b
+	opLocalGet  = 0x20
+	opLocalTee  = 0x22
+	opGlobalGet = 0x23
+	opGlobalSet = 0x24
+	opI32Const  = 0x41
+	opI32Eq     = 0x46
+	opI32Add    = 0x6a
+	opI32Sub    = 0x6b
+	opI32And    = 0x71
+)
+
+func i32Const(v int64) []byte {
+	return append([]byte{opI32Const}, sleb(v)...)
+}
+
+func funcType(params, results []byte) []byte {
+	return cat([]byte{0x60}, uleb(uint64(len(params))), params, uleb(uint64(len(results))), results)
+}
+
+func export(name string, kind byte, idx uint64) []byte {
+	return cat(wasmName(name), []byte{kind}, uleb(idx))
+}
+
+func body(locals []byte, code ...[]byte) []byte {
+	b := cat(locals, cat(code...), []byte{opEnd})
+	return append(uleb(uint64(len(b))), b...)
+}
+
+func data(offset int64, b []byte) []byte {
+	return cat([]byte{0}, i32Const(offset), []byte{opEnd}, wasmName(string(b)))
+}
+
+func u32s(vs ...uint32) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	return b
+}
+
+// testMapper is a core module implementing just enough of the mapper ABI
+// by hand: a bump allocator, metadata returning ("test", "1.2.3"), and
+// process-logs returning ok("ok\n"), or err("boom") for two logs. Every
+// post-return export increments the exported global "posted".
+func testMapper() []byte {
+	const mapper = mapperPrefix + "0.1.0#"
+	return cat(wasmMagic, coreVersion,
+		section(1, // types
+			funcType([]byte{i32, i32, i32, i32}, []byte{i32}),
+			funcType(nil, []byte{i32}),
+			funcType([]byte{i32}, nil),
+			funcType([]byte{i32, i32}, []byte{i32}),
+		),
+		section(3, uleb(0), uleb(1), uleb(2), uleb(3)), // functions
+		section(5, []byte{0x00, 0x01}),                 // one page of memory
+		section(6, // globals: heap, posted
+			cat([]byte{i32, 1}, i32Const(4096), []byte{opEnd}),
+			cat([]byte{i32, 1}, i32Const(0), []byte{opEnd}),
+		),
+		section(7,
+			export("memory", 2, 0),
+			export("cabi_realloc", 0, 0),
+			export(mapper+"metadata", 0, 1),
+			export("cabi_post_"+mapper+"metadata", 0, 2),
+			export(mapper+"process-logs", 0, 3),
+			export("cabi_post_"+mapper+"process-logs", 0, 2),
+			export("posted", 3, 1),
+		),
+		section(10,
+			// cabi_realloc(_, _, align, size): heap rounded up to align.
+			body(vec([]byte{1, i32}),
+				[]byte{opGlobalGet, 0, opLocalGet, 2, opI32Add}, i32Const(1), []byte{opI32Sub},
+				i32Const(0), []byte{opLocalGet, 2, opI32Sub, opI32And, opLocalTee, 4},
+				[]byte{opLocalGet, 3, opI32Add, opGlobalSet, 0, opLocalGet, 4}),
+			body(vec(), i32Const(16)),
+			body(vec(), []byte{opGlobalGet, 1}, i32Const(1), []byte{opI32Add, opGlobalSet, 1}),
+			body(vec(), i32Const(96), i32Const(64), []byte{opLocalGet, 1}, i32Const(2), []byte{opI32Eq, opSelect}),
+		),
+		section(11,
+			data(16, u32s(32, 4, 40, 5)),
+			data(32, []byte("test")),
+			data(40, []byte("1.2.3")),
+			data(64, u32s(0, 80, 3)),
+			data(80, []byte("ok\n")),
+			data(96, u32s(1, 112, 4)),
+			data(112, []byte("boom")),
+		),
+	)
+}
+
+// moduleSection is a component section embedding the core module mod.
+func moduleSection(mod []byte) []byte {
+	return cat([]byte{1}, uleb(uint64(len(mod))), mod)
+}
+
+// component wraps sections in a component binary.
+func component(sections ...[]byte) []byte {
+	return cat(wasmMagic, componentVersion, cat(sections...))
+}
+
+// testPlugin loads bin as a plugin.
+func testPlugin(t *testing.T, bin []byte) *plugin {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	if err := os.WriteFile(path, bin, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := load(context.Background(), path, newHost(map[string]string{}, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.close)
+	return p
+}
+
+// testGuest returns the memory and allocator of a fresh test module.
+func testGuest(t *testing.T) guest {
+	p := testPlugin(t, testMapper())
+	return guest{ctx: p.ctx, mod: p.mod}
+}
+
+// trap returns the message fn panics with, or "".
+func trap(fn func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			var e errTrap
+			if err, ok := r.(error); ok && errors.As(err, &e) {
+				msg = string(e)
+			} else {
+				panic(r)
+			}
+		}
+	}()
+	fn()
+	return ""
+}
+
+func TestULEB128(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		v    uint64
+		n    int
+		fail bool
+	}{
+		{in: []byte{0x00}, v: 0, n: 1},
+		{in: []byte{0x7f}, v: 127, n: 1},
+		{in: []byte{0x80, 0x01}, v: 128, n: 2},
+		{in: []byte{0xe5, 0x8e, 0x26}, v: 624485, n: 3},
+		{in: []byte{0x05, 0xff}, v: 5, n: 1}, // trailing bytes are not read
+		{in: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, v: math.MaxUint64, n: 10},
+		{in: nil, fail: true},
+		{in: []byte{0x80}, fail: true},
+		{in: bytes.Repeat([]byte{0x80}, 10), fail: true},
+	}
+	for _, tt := range tests {
+		v, n, err := uleb128(tt.in)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("uleb128(% x) = %d, %d; want error", tt.in, v, n)
+			}
+			continue
+		}
+		if err != nil || v != tt.v || n != tt.n {
+			t.Errorf("uleb128(% x) = %d, %d, %v; want %d, %d", tt.in, v, n, err, tt.v, tt.n)
+		}
+	}
+}
+
+func TestCoreModule(t *testing.T) {
+	core := testMapper()
+	other := cat(wasmMagic, coreVersion, section(1))
+	big := cat(wasmMagic, coreVersion, section(1), section(0, wasmName("padding padding padding")))
+	custom := section(0, wasmName("component-name"))
+	tests := []struct {
+		name string
+		bin  []byte
+		want []byte
+		err  string
+	}{
+		{"core module", core, core, ""},
+		{"mapper module", component(custom, moduleSection(other), moduleSection(core), custom), core, ""},
+		{"largest module", component(moduleSection(other), moduleSection(big)), big, ""},
+		{"no core module", component(custom), nil, "component contains no core module"},
+		{"empty component", component(), nil, "component contains no core module"},
+		{"truncated section", component([]byte{1, 10, 0}), nil, "truncated component section"},
+		{"malformed size", component([]byte{1, 0x80}), nil, "malformed LEB128"},
+		{"not wasm", []byte("\x7fELF\x02\x01\x01\x00"), nil, "not a wasm binary"},
+		{"too short", wasmMagic, nil, "not a wasm binary"},
+		{"unknown version", cat(wasmMagic, []byte{2, 0, 0, 0}), nil, "unsupported wasm version 02 00 00 00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := coreModule(tt.bin)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("coreModule returned %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+// scalarValue returns s's payload, with bytes as a []byte.
+func scalarValue(s log.Scalar) any {
+	switch s.Tag() {
+	case 0:
+		return *s.Str()
+	case 1:
+		return *s.Int()
+	case 2:
+		return *s.Float()
+	case 3:
+		return *s.Boolean()
+	default:
+		return string(s.Bytes().Slice())
+	}
+}
+
+var scalars = []log.Scalar{
+	log.ScalarStr("héllo"),
+	log.ScalarStr(""),
+	log.ScalarInt(-5),
+	log.ScalarInt(math.MaxInt64),
+	log.ScalarFloat(1.5),
+	log.ScalarFloat(math.Inf(-1)),
+	log.ScalarBoolean(true),
+	log.ScalarBoolean(false),
+	log.ScalarBytes(cm.ToList([]byte{0, 1, 0xff})),
+	log.ScalarBytes(cm.ToList([]byte{})),
+}
+
+func TestScalarLayout(t *testing.T) {
+	g := testGuest(t)
+	for _, s := range scalars {
+		addr := g.alloc(scalarSize, scalarAlign)
+		g.putScalar(addr, s)
+		if tag := g.u8(addr); tag != s.Tag() {
+			t.Errorf("%s: tag %d, want %d", scalarString(s), tag, s.Tag())
+		}
+		if got := g.scalarAt(addr); got.Tag() != s.Tag() || scalarValue(got) != scalarValue(s) {
+			t.Errorf("scalarAt after putScalar(%s) = %s", scalarString(s), scalarString(got))
+		}
+	}
+
+	// Flattened params: (tag, i64 payload, i32 length).
+	str := g.alloc(5, 1)
+	g.putBytes(str, []byte("héllo"[:5]))
+	lifts := []struct {
+		tag uint32
+		p1  uint64
+		p2  uint32
+		ok  log.Scalar
+	}{
+		{0, uint64(str), 5, log.ScalarStr("héllo"[:5])},
+		{1, uint64(math.MaxUint64), 0, log.ScalarInt(-1)},
+		{2, math.Float64bits(-2.25), 0, log.ScalarFloat(-2.25)},
+		{3, 1, 0, log.ScalarBoolean(true)},
+		{3, 0, 0, log.ScalarBoolean(false)},
+		{4, uint64(str), 2, log.ScalarBytes(cm.ToList([]byte("hé"[:2])))},
+	}
+	for _, tt := range lifts {
+		if got := g.liftScalar(tt.tag, tt.p1, tt.p2); got.Tag() != tt.ok.Tag() || scalarValue(got) != scalarValue(tt.ok) {
+			t.Errorf("liftScalar(%d, %d, %d) = %s, want %s", tt.tag, tt.p1, tt.p2, scalarString(got), scalarString(tt.ok))
+		}
+	}
+}
+
+func TestGuestMemory(t *testing.T) {
+	g := testGuest(t)
+
+	for _, align := range []uint32{1, 2, 4, 8, 16} {
+		g.alloc(1, 1)
+		if ptr := g.alloc(3, align); ptr%align != 0 {
+			t.Errorf("alloc(3, %d) = %d", align, ptr)
+		}
+	}
+	if ptr := g.alloc(0, 8); ptr != 8 {
+		t.Errorf("alloc(0, 8) = %d, want the alignment", ptr)
+	}
+
+	rec := g.alloc(16, 4)
+	for _, s := range []string{"", "a", strings.Repeat("xyz", 100)} {
+		g.putString(rec, s)
+		if got := g.stringAt(rec); got != s {
+			t.Errorf("stringAt after putString(%q) = %q", s, got)
+		}
+	}
+
+	base := g.putList(rec+8, 3, 24, 8)
+	if base%8 != 0 || g.u32(rec+8) != base || g.u32(rec+12) != 3 {
+		t.Errorf("putList stored (%d, %d), returned %d", g.u32(rec+8), g.u32(rec+12), base)
+	}
+	g.putU64(base+16, math.MaxUint64-1)
+	g.putU8(base+17, 0)
+	if got := g.u64(base + 16); got != 0xffffffffffff00fe {
+		t.Errorf("u64 = %#x", got)
+	}
+	if got := g.u16(base + 16); got != 0x00fe {
+		t.Errorf("u16 = %#x", got)
+	}
+
+	const outside = 1 << 16
+	for name, fn := range map[string]func(){
+		"read":     func() { g.read(outside-2, 4) },
+		"u8":       func() { g.u8(outside) },
+		"u32":      func() { g.u32(outside - 3) },
+		"u64":      func() { g.u64(outside - 7) },
+		"putU32":   func() { g.putU32(outside-1, 1) },
+		"putBytes": func() { g.putBytes(outside-1, []byte{1, 2}) },
+	} {
+		if msg := trap(fn); !strings.HasPrefix(msg, "out of bounds") {
+			t.Errorf("%s outside memory: trap %q", name, msg)
+		}
+	}
+}
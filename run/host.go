@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// hostFunc implements one imported function. Params arrive on stack in their
+// flattened canonical ABI form; results are written back to stack[0].
+type hostFunc func(g guest, stack []uint64)
+
+type cacheEntry struct {
+	value   log.Scalar
+	expires time.Time
+}
+
+// cachedResponse is a remote response kept for the request's cache-ttl-ms.
+type cachedResponse struct {
+	resp    response
+	expires time.Time
+}
+
+// host holds the state behind the tangent:logs imports.
+type host struct {
+	mu      sync.Mutex
+	views   map[uint32]*jsonlog.View
+	next    uint32
+	config  map[string]string
+	cache   map[string]cacheEntry
+	locks   map[string]bool
+	client  *http.Client
+	offline bool
+	calls   map[string]uint64 // host calls by import, for -bench
+
+	// responses caches remote responses by requestKey.
+	responses map[string]cachedResponse
+}
+
+func newHost(config map[string]string, offline bool) *host {
+	return &host{
+		views:   map[uint32]*jsonlog.View{},
+		next:    1,
+		config:  config,
+		cache:   map[string]cacheEntry{},
+		locks:   map[string]bool{},
+		client:  &http.Client{Timeout: 30 * time.Second},
+		offline: offline,
+		calls:   map[string]uint64{},
+
+		responses: map[string]cachedResponse{},
+	}
+}
+
+// open registers a log and returns its resource handle.
+func (h *host) open(v *jsonlog.View) uint32 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	handle := h.next
+	h.next++
+	h.views[handle] = v
+	return handle
+}
+
+// dropAll releases every handle the guest did not drop itself.
+func (h *host) dropAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clear(h.views)
+}
+
+func (h *host) view(handle uint32) *jsonlog.View {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.views[handle]
+	if !ok {
+		panic(errTrap(fmt.Sprintf("unknown logview handle %d", handle)))
+	}
+	return v
+}
+
+// funcs returns the tangent:logs imports keyed by unversioned interface name
+// and function name.
+func (h *host) funcs() map[string]map[string]hostFunc {
+	return map[string]map[string]hostFunc{
+		"tangent:logs/log": {
+			"[resource-drop]logview": func(g guest, stack []uint64) {
+				h.mu.Lock()
+				delete(h.views, api.DecodeU32(stack[0]))
+				h.mu.Unlock()
+			},
+			"[method]logview.get": func(g guest, stack []uint64) {
+				v, path, ret := h.view(api.DecodeU32(stack[0])), g.readString(api.DecodeU32(stack[1]), api.DecodeU32(stack[2])), api.DecodeU32(stack[3])
+				opt := v.Get(path)
+				if opt.None() {
+					g.putU8(ret, 0)
+					return
+				}
+				g.putU8(ret, 1)
+				g.putScalar(ret+8, opt.Value())
+			},
+			"[method]logview.get-list": func(g guest, stack []uint64) {
+				v, path, ret := h.view(api.DecodeU32(stack[0])), g.readString(api.DecodeU32(stack[1]), api.DecodeU32(stack[2])), api.DecodeU32(stack[3])
+				opt := v.GetList(path)
+				if opt.None() {
+					g.putU8(ret, 0)
+					return
+				}
+				g.putU8(ret, 1)
+				items := opt.Value().Slice()
+				base := g.putList(ret+4, len(items), scalarSize, scalarAlign)
+				for i, s := range items {
+					g.putScalar(base+uint32(i)*scalarSize, s)
+				}
+			},
+			"[method]logview.get-map": func(g guest, stack []uint64) {
+				v, path, ret := h.view(api.DecodeU32(stack[0])), g.readString(api.DecodeU32(stack[1]), api.DecodeU32(stack[2])), api.DecodeU32(stack[3])
+				opt := v.GetMap(path)
+				if opt.None() {
+					g.putU8(ret, 0)
+					return
+				}
+				g.putU8(ret, 1)
+				fields := opt.Value().Slice()
+				base := g.putList(ret+4, len(fields), fieldSize, scalarAlign)
+				for i, f := range fields {
+					addr := base + uint32(i)*fieldSize
+					g.putString(addr, f.F0)
+					g.putScalar(addr+8, f.F1)
+				}
+			},
+			"[method]logview.has": func(g guest, stack []uint64) {
+				v, path := h.view(api.DecodeU32(stack[0])), g.readString(api.DecodeU32(stack[1]), api.DecodeU32(stack[2]))
+				stack[0] = boolResult(v.Has(path))
+			},
+			"[method]logview.keys": func(g guest, stack []uint64) {
+				v, path, ret := h.view(api.DecodeU32(stack[0])), g.readString(api.DecodeU32(stack[1]), api.DecodeU32(stack[2])), api.DecodeU32(stack[3])
+				keys := v.Keys(path).Slice()
+				base := g.putList(ret, len(keys), stringSize, 4)
+				for i, k := range keys {
+					g.putString(base+uint32(i)*stringSize, k)
+				}
+			},
+			"[method]logview.len": func(g guest, stack []uint64) {
+				v, path, ret := h.view(api.DecodeU32(stack[0])), g.readString(api.DecodeU32(stack[1]), api.DecodeU32(stack[2])), api.DecodeU32(stack[3])
+				opt := v.Len(path)
+				if opt.None() {
+					g.putU8(ret, 0)
+					return
+				}
+				g.putU8(ret, 1)
+				g.putU32(ret+4, opt.Value())
+			},
+			"[method]logview.log": func(g guest, stack []uint64) {
+				v, ret := h.view(api.DecodeU32(stack[0])), api.DecodeU32(stack[1])
+				g.putString(ret, v.Log())
+			},
+		},
+		"tangent:logs/config": {
+			"get": func(g guest, stack []uint64) {
+				key, ret := g.readString(api.DecodeU32(stack[0]), api.DecodeU32(stack[1])), api.DecodeU32(stack[2])
+				val, ok := h.config[key]
+				if !ok {
+					g.putU8(ret, 0)
+					return
+				}
+				g.putU8(ret, 1)
+				g.putString(ret+4, val)
+			},
+		},
+		"tangent:logs/lock": {
+			"acquire": func(g guest, stack []uint64) {
+				key := g.readString(api.DecodeU32(stack[0]), api.DecodeU32(stack[1]))
+				h.mu.Lock()
+				held := h.locks[key]
+				h.locks[key] = true
+				h.mu.Unlock()
+				stack[0] = boolResult(!held)
+			},
+			"release": func(g guest, stack []uint64) {
+				key := g.readString(api.DecodeU32(stack[0]), api.DecodeU32(stack[1]))
+				h.mu.Lock()
+				delete(h.locks, key)
+				h.mu.Unlock()
+			},
+		},
+		"tangent:logs/cache": {
+			"get": func(g guest, stack []uint64) {
+				key, ret := g.readString(api.DecodeU32(stack[0]), api.DecodeU32(stack[1])), api.DecodeU32(stack[2])
+				h.mu.Lock()
+				e, ok := h.cache[key]
+				if ok && !e.expires.IsZero() && time.Now().After(e.expires) {
+					delete(h.cache, key)
+					ok = false
+				}
+				h.mu.Unlock()
+				g.putU8(ret, 0)
+				if !ok {
+					g.putU8(ret+8, 0)
+					return
+				}
+				g.putU8(ret+8, 1)
+				g.putScalar(ret+16, e.value)
+			},
+			"set": func(g guest, stack []uint64) {
+				key := g.readString(api.DecodeU32(stack[0]), api.DecodeU32(stack[1]))
+				value := g.liftScalar(api.DecodeU32(stack[2]), stack[3], api.DecodeU32(stack[4]))
+				ret := api.DecodeU32(stack[7])
+				e := cacheEntry{value: value}
+				if api.DecodeU32(stack[5]) != 0 {
+					e.expires = time.Now().Add(time.Duration(stack[6]) * time.Millisecond)
+				}
+				h.mu.Lock()
+				h.cache[key] = e
+				h.mu.Unlock()
+				g.putU8(ret, 0)
+			},
+			"del": func(g guest, stack []uint64) {
+				key, ret := g.readString(api.DecodeU32(stack[0]), api.DecodeU32(stack[1])), api.DecodeU32(stack[2])
+				h.mu.Lock()
+				_, ok := h.cache[key]
+				delete(h.cache, key)
+				h.mu.Unlock()
+				g.putU8(ret, 0)
+				g.putU8(ret+4, uint8(boolResult(ok)))
+			},
+		},
+		"tangent:logs/remote": {
+			"call-batch": func(g guest, stack []uint64) {
+				base, n, ret := api.DecodeU32(stack[0]), api.DecodeU32(stack[1]), api.DecodeU32(stack[2])
+				if h.offline {
+					g.putU8(ret, 1)
+					g.putString(ret+4, "remote calls are disabled (-offline)")
+					return
+				}
+				resps := make([]response, n)
+				for i := uint32(0); i < n; i++ {
+					resps[i] = h.call(g.ctx, g.request(base+i*requestSize))
+				}
+				g.putU8(ret, 0)
+				out := g.putList(ret+4, len(resps), responseSize, 4)
+				for i, r := range resps {
+					g.putResponse(out+uint32(i)*responseSize, r)
+				}
+			},
+		},
+	}
+}
+
+func boolResult(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type request struct {
+	id        string
+	method    string
+	url       string
+	headers   [][2]string
+	body      []byte
+	timeoutMs *uint32
+	cacheTTL  *uint32 // ms
+}
+
+type response struct {
+	id      string
+	status  uint16
+	headers [][2]string
+	body    []byte
+	err     string
+}
+
+var methods = [...]string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch}
+
+func (g guest) request(addr uint32) request {
+	r := request{
+		id:  g.stringAt(addr),
+		url: g.stringAt(addr + 12),
+	}
+	if m := g.u8(addr + 8); int(m) < len(methods) {
+		r.method = methods[m]
+	}
+	hptr, hlen := g.u32(addr+20), g.u32(addr+24)
+	for i := uint32(0); i < hlen; i++ {
+		h := hptr + i*headerPairSize
+		r.headers = append(r.headers, [2]string{g.stringAt(h), g.stringAt(h + 8)})
+	}
+	r.body = g.read(g.u32(addr+28), g.u32(addr+32))
+	if g.u8(addr+36) != 0 {
+		ms := g.u32(addr + 40)
+		r.timeoutMs = &ms
+	}
+	if g.u8(addr+44) != 0 {
+		ms := g.u32(addr + 48)
+		r.cacheTTL = &ms
+	}
+	return r
+}
+
+func (g guest) putResponse(addr uint32, r response) {
+	g.putString(addr, r.id)
+	g.mem().WriteUint16Le(addr+8, r.status)
+	hdrs := g.putList(addr+12, len(r.headers), headerPairSize, 4)
+	for i, h := range r.headers {
+		g.putString(hdrs+uint32(i)*headerPairSize, h[0])
+		g.putString(hdrs+uint32(i)*headerPairSize+8, h[1])
+	}
+	body := g.putList(addr+20, len(r.body), 1, 1)
+	g.putBytes(body, r.body)
+	if r.err == "" {
+		g.putU8(addr+28, 0)
+		return
+	}
+	g.putU8(addr+28, 1)
+	g.putString(addr+32, r.err)
+}
+
+// requestKey identifies the requests a cached response answers: all fields
+// but the ID and the options.
+func requestKey(r request) string {
+	return fmt.Sprintf("%s %s %q %q", r.method, r.url, r.headers, r.body)
+}
+
+// call performs r, answering from the response cache while an earlier
+// successful response to the same request is within its cache-ttl-ms.
+func (h *host) call(ctx context.Context, r request) response {
+	key := requestKey(r)
+	h.mu.Lock()
+	c, ok := h.responses[key]
+	if ok && time.Now().After(c.expires) {
+		delete(h.responses, key)
+		ok = false
+	}
+	h.mu.Unlock()
+	if ok {
+		c.resp.id = r.id
+		return c.resp
+	}
+
+	out := h.do(ctx, r)
+	if r.cacheTTL != nil && *r.cacheTTL > 0 && out.err == "" {
+		h.mu.Lock()
+		h.responses[key] = cachedResponse{resp: out, expires: time.Now().Add(time.Duration(*r.cacheTTL) * time.Millisecond)}
+		h.mu.Unlock()
+	}
+	return out
+}
+
+// do performs r over HTTP.
+func (h *host) do(ctx context.Context, r request) response {
+	out := response{id: r.id}
+	if r.timeoutMs != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*r.timeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, bytes.NewReader(r.body))
+	if err != nil {
+		out.err = err.Error()
+		return out
+	}
+	for _, hdr := range r.headers {
+		req.Header.Add(hdr[0], hdr[1])
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		out.err = err.Error()
+		return out
+	}
+	defer resp.Body.Close()
+	out.status = uint16(resp.StatusCode)
+	for name, vals := range resp.Header {
+		for _, v := range vals {
+			out.headers = append(out.headers, [2]string{strings.ToLower(name), v})
+		}
+	}
+	if out.body, err = io.ReadAll(resp.Body); err != nil {
+		out.err = err.Error()
+	}
+	return out
+}
+
+// scalarString renders a scalar for diagnostics.
+func scalarString(s log.Scalar) string {
+	switch s.Tag() {
+	case 0:
+		return fmt.Sprintf("%q", *s.Str())
+	case 1:
+		return fmt.Sprint(*s.Int())
+	case 2:
+		return fmt.Sprint(*s.Float())
+	case 3:
+		return fmt.Sprint(*s.Boolean())
+	default:
+		return fmt.Sprintf("bytes(%d)", len(s.Bytes().Slice()))
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+)
+
+// hostCall runs the host import iface#name with params, a string argument
+// being passed as its (ptr, len) pair, and a return area appended when ret
+// is non-zero. It returns stack[0] and the return area.
+func hostCall(t *testing.T, h *host, g guest, iface, name string, ret uint32, params ...any) (uint64, uint32) {
+	t.Helper()
+	fn := h.funcs()[iface][name]
+	if fn == nil {
+		t.Fatalf("no host function %s#%s", iface, name)
+	}
+	var stack []uint64
+	for _, p := range params {
+		switch p := p.(type) {
+		case string:
+			ptr := g.alloc(uint32(len(p)), 1)
+			g.putBytes(ptr, []byte(p))
+			stack = append(stack, uint64(ptr), uint64(len(p)))
+		case uint32:
+			stack = append(stack, uint64(p))
+		case uint64:
+			stack = append(stack, p)
+		default:
+			t.Fatalf("param %T", p)
+		}
+	}
+	var area uint32
+	if ret > 0 {
+		area = g.alloc(ret, 8)
+		stack = append(stack, uint64(area))
+	}
+	stack = append(stack, 0) // results may outnumber params
+	fn(g, stack)
+	return stack[0], area
+}
+
+func TestLogviewImports(t *testing.T) {
+	g, h := testGuest(t), newHost(nil, true)
+	v, err := jsonlog.Parse([]byte(`{"a":{"b":1,"c":"x"},"l":[true,2.5],"e":[],"s":"str"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lv := h.open(v)
+	const iface = "tangent:logs/log"
+
+	// option<scalar>
+	_, ret := hostCall(t, h, g, iface, "[method]logview.get", 24, lv, "a.c")
+	if g.u8(ret) != 1 || scalarValue(g.scalarAt(ret+8)) != "x" {
+		t.Errorf("get(a.c) = tag %d, %s", g.u8(ret), scalarString(g.scalarAt(ret+8)))
+	}
+	for _, path := range []string{"missing", "a", "l"} {
+		if _, ret := hostCall(t, h, g, iface, "[method]logview.get", 24, lv, path); g.u8(ret) != 0 {
+			t.Errorf("get(%s) is some", path)
+		}
+	}
+
+	// option<list<scalar>>
+	_, ret = hostCall(t, h, g, iface, "[method]logview.get-list", 12, lv, "l")
+	if g.u8(ret) != 1 || g.u32(ret+8) != 2 {
+		t.Fatalf("get-list(l) = tag %d, len %d", g.u8(ret), g.u32(ret+8))
+	}
+	base := g.u32(ret + 4)
+	if base%scalarAlign != 0 || scalarValue(g.scalarAt(base)) != true || scalarValue(g.scalarAt(base+scalarSize)) != 2.5 {
+		t.Errorf("get-list(l) elements at %d: %s, %s", base, scalarString(g.scalarAt(base)), scalarString(g.scalarAt(base+scalarSize)))
+	}
+	if _, ret := hostCall(t, h, g, iface, "[method]logview.get-list", 12, lv, "e"); g.u8(ret) != 1 || g.u32(ret+8) != 0 {
+		t.Errorf("get-list(e) = tag %d, len %d; want an empty list", g.u8(ret), g.u32(ret+8))
+	}
+	if _, ret := hostCall(t, h, g, iface, "[method]logview.get-list", 12, lv, "s"); g.u8(ret) != 0 {
+		t.Error("get-list(s) is some")
+	}
+
+	// option<list<tuple<string, scalar>>>
+	_, ret = hostCall(t, h, g, iface, "[method]logview.get-map", 12, lv, "a")
+	if g.u8(ret) != 1 || g.u32(ret+8) != 2 {
+		t.Fatalf("get-map(a) = tag %d, len %d", g.u8(ret), g.u32(ret+8))
+	}
+	base = g.u32(ret + 4)
+	got := map[string]any{}
+	for i := uint32(0); i < 2; i++ {
+		f := base + i*fieldSize
+		got[g.stringAt(f)] = scalarValue(g.scalarAt(f + 8))
+	}
+	if want := map[string]any{"b": int64(1), "c": "x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("get-map(a) = %v, want %v", got, want)
+	}
+	if _, ret := hostCall(t, h, g, iface, "[method]logview.get-map", 12, lv, "l"); g.u8(ret) != 0 {
+		t.Error("get-map(l) is some")
+	}
+
+	// list<string>
+	_, ret = hostCall(t, h, g, iface, "[method]logview.keys", 8, lv, "")
+	var keys []string
+	for i := uint32(0); i < g.u32(ret+4); i++ {
+		keys = append(keys, g.stringAt(g.u32(ret)+i*stringSize))
+	}
+	if want := []string{"a", "e", "l", "s"}; !slices.Equal(keys, want) {
+		t.Errorf("keys() = %q, want %q", keys, want)
+	}
+
+	// option<u32>
+	if _, ret := hostCall(t, h, g, iface, "[method]logview.len", 8, lv, "l"); g.u8(ret) != 1 || g.u32(ret+4) != 2 {
+		t.Errorf("len(l) = tag %d, %d", g.u8(ret), g.u32(ret+4))
+	}
+	if _, ret := hostCall(t, h, g, iface, "[method]logview.len", 8, lv, "a.b"); g.u8(ret) != 0 {
+		t.Error("len(a.b) is some")
+	}
+
+	// bool
+	for path, want := range map[string]uint64{"a.b": 1, "e": 1, "a.z": 0} {
+		if got, _ := hostCall(t, h, g, iface, "[method]logview.has", 0, lv, path); got != want {
+			t.Errorf("has(%s) = %d, want %d", path, got, want)
+		}
+	}
+
+	// string
+	if _, ret := hostCall(t, h, g, iface, "[method]logview.log", 8, lv); g.stringAt(ret) != v.Log() {
+		t.Errorf("log() = %s", g.stringAt(ret))
+	}
+
+	hostCall(t, h, g, iface, "[resource-drop]logview", 0, lv)
+	if msg := trap(func() { hostCall(t, h, g, iface, "[method]logview.has", 0, lv, "a") }); msg == "" {
+		t.Error("a dropped handle stayed usable")
+	}
+}
+
+func TestStateImports(t *testing.T) {
+	g, h := testGuest(t), newHost(map[string]string{"k": "v"}, true)
+
+	// config.get: option<string>
+	if _, ret := hostCall(t, h, g, "tangent:logs/config", "get", 12, "k"); g.u8(ret) != 1 || g.stringAt(ret+4) != "v" {
+		t.Errorf("config get(k) = tag %d, %q", g.u8(ret), g.stringAt(ret+4))
+	}
+	if _, ret := hostCall(t, h, g, "tangent:logs/config", "get", 12, "missing"); g.u8(ret) != 0 {
+		t.Error("config get(missing) is some")
+	}
+
+	// cache.get: result<option<scalar>, string>
+	const cache = "tangent:logs/cache"
+	get := func(key string) (log.Scalar, bool) {
+		_, ret := hostCall(t, h, g, cache, "get", 32, key)
+		if g.u8(ret) != 0 {
+			t.Fatalf("cache get(%s) failed", key)
+		}
+		return g.scalarAt(ret + 16), g.u8(ret+8) == 1
+	}
+	// set(key, scalar flattened as (tag, i64, i32), option<u64> as (tag, u64)): result<_, string>
+	set := func(key string, v int64, ttl *uint64) {
+		var some, ms uint64
+		if ttl != nil {
+			some, ms = 1, *ttl
+		}
+		if _, ret := hostCall(t, h, g, cache, "set", 8, key, uint32(1), uint64(v), uint32(0), some, ms); g.u8(ret) != 0 {
+			t.Fatalf("cache set(%s) failed", key)
+		}
+	}
+	if _, ok := get("n"); ok {
+		t.Error("cache get(n) before set is some")
+	}
+	set("n", 7, nil)
+	if v, ok := get("n"); !ok || scalarValue(v) != int64(7) {
+		t.Errorf("cache get(n) = %s, %t", scalarString(v), ok)
+	}
+	ttl := uint64(1)
+	set("t", 1, &ttl)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := get("t"); ok {
+		t.Error("cache entry outlived its ttl")
+	}
+	// del: result<bool, string>
+	for _, want := range []uint8{1, 0} {
+		if _, ret := hostCall(t, h, g, cache, "del", 8, "n"); g.u8(ret) != 0 || g.u8(ret+4) != want {
+			t.Errorf("cache del(n) = tag %d, %d; want %d", g.u8(ret), g.u8(ret+4), want)
+		}
+	}
+
+	// lock: bool
+	for _, want := range []uint64{1, 0} {
+		if got, _ := hostCall(t, h, g, "tangent:logs/lock", "acquire", 0, "l"); got != want {
+			t.Errorf("acquire(l) = %d, want %d", got, want)
+		}
+	}
+	hostCall(t, h, g, "tangent:logs/lock", "release", 0, "l")
+	if got, _ := hostCall(t, h, g, "tangent:logs/lock", "acquire", 0, "l"); got != 1 {
+		t.Error("acquire after release failed")
+	}
+
+	// remote.call-batch: result<list<response>, string>
+	if _, ret := hostCall(t, h, g, "tangent:logs/remote", "call-batch", 12, uint32(0), uint32(0)); g.u8(ret) != 1 || g.stringAt(ret+4) == "" {
+		t.Errorf("offline call-batch = tag %d, %q", g.u8(ret), g.stringAt(ret+4))
+	}
+}
+
+func TestRequestLayout(t *testing.T) {
+	g := testGuest(t)
+	timeout, ttl := uint32(250), uint32(60000)
+	want := request{
+		id:        "r1",
+		method:    http.MethodPost,
+		url:       "http://example.com/x",
+		headers:   [][2]string{{"a", "1"}, {"b", "2"}},
+		body:      []byte("{}"),
+		timeoutMs: &timeout,
+		cacheTTL:  &ttl,
+	}
+	addr := g.alloc(requestSize, 4)
+	g.putString(addr, want.id)
+	g.putU8(addr+8, 1)
+	g.putString(addr+12, want.url)
+	hdrs := g.putList(addr+20, len(want.headers), headerPairSize, 4)
+	for i, h := range want.headers {
+		g.putString(hdrs+uint32(i)*headerPairSize, h[0])
+		g.putString(hdrs+uint32(i)*headerPairSize+8, h[1])
+	}
+	g.putBytes(g.putList(addr+28, len(want.body), 1, 1), want.body)
+	g.putU8(addr+36, 1)
+	g.putU32(addr+40, timeout)
+	g.putU8(addr+44, 1)
+	g.putU32(addr+48, ttl)
+	if got := g.request(addr); !reflect.DeepEqual(got, want) {
+		t.Errorf("request = %+v, want %+v", got, want)
+	}
+	g.putU8(addr+36, 0)
+	g.putU8(addr+44, 0)
+	if got := g.request(addr); got.timeoutMs != nil || got.cacheTTL != nil {
+		t.Errorf("request options = %v, %v; want none", got.timeoutMs, got.cacheTTL)
+	}
+
+	for _, r := range []response{
+		{id: "r1", status: 201, headers: [][2]string{{"content-type", "text/plain"}}, body: []byte("hi")},
+		{id: "r2", err: "refused"},
+	} {
+		addr := g.alloc(responseSize, 4)
+		g.putResponse(addr, r)
+		got := response{id: g.stringAt(addr), status: g.u16(addr + 8)}
+		for i := uint32(0); i < g.u32(addr+16); i++ {
+			h := g.u32(addr+12) + i*headerPairSize
+			got.headers = append(got.headers, [2]string{g.stringAt(h), g.stringAt(h + 8)})
+		}
+		if n := g.u32(addr + 24); n > 0 {
+			got.body = g.read(g.u32(addr+20), n)
+		}
+		if g.u8(addr+28) == 1 {
+			got.err = g.stringAt(addr + 32)
+		}
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("putResponse(%+v) stored %+v", r, got)
+		}
+	}
+}
+
+func TestRemoteCacheTTL(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	h := newHost(nil, false)
+	ttl := uint32(60000)
+	call := func(id, path string, ttl *uint32) response {
+		t.Helper()
+		resp := h.call(context.Background(), request{id: id, method: http.MethodGet, url: srv.URL + path, cacheTTL: ttl})
+		if resp.err != "" || resp.id != id || string(resp.body) != path {
+			t.Fatalf("call %s %s = %+v", id, path, resp)
+		}
+		return resp
+	}
+
+	call("1", "/a", &ttl)
+	call("2", "/a", &ttl)
+	call("3", "/a", nil)
+	if n := hits.Load(); n != 1 {
+		t.Errorf("%d requests for a cached response, want 1", n)
+	}
+	call("4", "/b", &ttl)
+	call("5", "/c", nil)
+	call("6", "/c", nil)
+	if n := hits.Load(); n != 4 {
+		t.Errorf("%d requests in all, want 4", n)
+	}
+
+	short := uint32(1)
+	call("7", "/d", &short)
+	time.Sleep(5 * time.Millisecond)
+	call("8", "/d", &short)
+	if hits.Load() != 6 {
+		t.Errorf("an expired response was reused")
+	}
+}
@@ -0,0 +1,440 @@
+// Command run executes a compiled mapper plugin against newline-delimited JSON
+// without a Tangent deployment:
+//
+//	go run github.com/telophasehq/tangent-sdk-go/run [flags] plugin.wasm [input.jsonl]
+//
+// It provides host implementations of tangent:logs/log, cache, lock, config
+// and remote, feeds each input line through process-logs and writes the
+// mapper's output to stdout. Input defaults to stdin.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const mapperPrefix = "tangent:logs/mapper@"
+
+type configFlag map[string]string
+
+func (c configFlag) String() string { return "" }
+
+func (c configFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("want key=value, got %q", s)
+	}
+	c[k] = v
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+
+	config := configFlag{}
+	batch := flag.Int("batch", 256, "logs per process-logs call")
+	offline := flag.Bool("offline", false, "fail remote calls instead of performing them")
+	verbose := flag.Bool("v", false, "print plugin metadata and selectors to stderr")
+	bench := flag.Bool("bench", false, "report throughput and host calls per log to stderr")
+	flag.Var(config, "config", "config `key=value` visible through tangent:logs/config (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: run [flags] plugin.wasm [input.jsonl]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 || *batch < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 2 {
+		f, err := os.Open(flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	ctx := context.Background()
+	p, err := load(ctx, flag.Arg(0), newHost(config, *offline))
+	if err != nil {
+		log.Fatalf("load %s: %v", flag.Arg(0), err)
+	}
+	defer p.close()
+
+	if *verbose {
+		name, version, err := p.metadata()
+		if err != nil {
+			log.Fatalf("metadata: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "plugin %s@%s\n", name, version)
+		sels, err := p.probe()
+		if err != nil {
+			log.Fatalf("probe: %v", err)
+		}
+		for i, s := range sels {
+			fmt.Fprintf(os.Stderr, "selector %d: %s\n", i, s)
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	clear(p.host.calls)
+	start := time.Now()
+	records := 0
+
+	failed := false
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var (
+		views []*jsonlog.View
+		first int
+		line  int
+	)
+	flush := func() {
+		if len(views) == 0 {
+			return
+		}
+		res, err := p.processLogs(views)
+		if err != nil {
+			failed = true
+			fmt.Fprintf(os.Stderr, "lines %d-%d: %v\n", first, line, err)
+		} else {
+			out.Write(res)
+		}
+		views = views[:0]
+	}
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		v, err := jsonlog.Parse(raw)
+		if err != nil {
+			failed = true
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			continue
+		}
+		if len(views) == 0 {
+			first = line
+		}
+		views = append(views, v)
+		records++
+		if len(views) == *batch {
+			flush()
+		}
+	}
+	flush()
+	if err := sc.Err(); err != nil {
+		log.Fatalf("read input: %v", err)
+	}
+	if *bench {
+		report(os.Stderr, records, time.Since(start), p.host.calls)
+	}
+	if failed {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+// report prints -bench results: throughput, then host calls per log by
+// import, busiest first.
+func report(w io.Writer, records int, elapsed time.Duration, calls map[string]uint64) {
+	per := func(n uint64) float64 {
+		if records == 0 {
+			return 0
+		}
+		return float64(n) / float64(records)
+	}
+	var total uint64
+	names := make([]string, 0, len(calls))
+	for name, n := range calls {
+		total += n
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if calls[names[i]] != calls[names[j]] {
+			return calls[names[i]] > calls[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintf(w, "%d logs in %v (%.0f logs/s, %v/log)\n", records, elapsed.Round(time.Microsecond),
+		float64(records)/elapsed.Seconds(), (elapsed / time.Duration(max(records, 1))).Round(time.Nanosecond))
+	fmt.Fprintf(w, "%d host calls (%.1f/log)\n", total, per(total))
+	for _, name := range names {
+		fmt.Fprintf(w, "  %10d  %6.1f/log  %s\n", calls[name], per(calls[name]), name)
+	}
+}
+
+// plugin is an instantiated mapper module.
+type plugin struct {
+	ctx     context.Context
+	rt      wazero.Runtime
+	mod     api.Module
+	host    *host
+	version string // mapper interface version, e.g. "0.1.0"
+}
+
+func load(ctx context.Context, path string, h *host) (*plugin, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	core, err := coreModule(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := wazero.NewRuntime(ctx)
+	p := &plugin{ctx: ctx, rt: rt, host: h}
+	compiled, err := rt.CompileModule(ctx, core)
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+
+	for name := range compiled.ExportedFunctions() {
+		if v, ok := strings.CutPrefix(name, mapperPrefix); ok {
+			p.version, _, _ = strings.Cut(v, "#")
+			break
+		}
+	}
+	if p.version == "" {
+		p.close()
+		return nil, errors.New("module does not export tangent:logs/mapper; was it built with tangent_sdk.Wire?")
+	}
+
+	if err := p.linkImports(compiled); err != nil {
+		p.close()
+		return nil, err
+	}
+
+	cfg := wazero.NewModuleConfig().
+		WithName("plugin").
+		WithStartFunctions().
+		WithStdout(os.Stderr).
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	p.mod, err = rt.InstantiateModule(ctx, compiled, cfg)
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+
+	if err := p.initialize(); err != nil {
+		p.close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// linkImports instantiates one host module per imported interface.
+func (p *plugin) linkImports(compiled wazero.CompiledModule) error {
+	impls := p.host.funcs()
+	for k, v := range wasiFuncs(os.Stderr) {
+		impls[k] = v
+	}
+
+	byModule := map[string][]api.FunctionDefinition{}
+	var order []string
+	for _, def := range compiled.ImportedFunctions() {
+		mod, _, _ := def.Import()
+		if _, ok := byModule[mod]; !ok {
+			order = append(order, mod)
+		}
+		byModule[mod] = append(byModule[mod], def)
+	}
+
+	for _, mod := range order {
+		if mod == wasi_snapshot_preview1.ModuleName {
+			if _, err := wasi_snapshot_preview1.Instantiate(p.ctx, p.rt); err != nil {
+				return err
+			}
+			continue
+		}
+		b := p.rt.NewHostModuleBuilder(mod)
+		for _, def := range byModule[mod] {
+			_, name, _ := def.Import()
+			impl := impls[unversioned(mod)][name]
+			switch {
+			case impl != nil:
+			case strings.HasPrefix(name, "[resource-drop]"):
+				impl = func(guest, []uint64) {}
+			default:
+				qualified := mod + "#" + name
+				impl = func(guest, []uint64) {
+					panic(errTrap("unsupported host import " + qualified))
+				}
+			}
+			b.NewFunctionBuilder().
+				WithGoModuleFunction(p.wrap(unversioned(mod)+"#"+name, impl), def.ParamTypes(), def.ResultTypes()).
+				Export(name)
+		}
+		if _, err := b.Instantiate(p.ctx); err != nil {
+			return fmt.Errorf("link %s: %w", mod, err)
+		}
+	}
+	return nil
+}
+
+func (p *plugin) wrap(name string, fn hostFunc) api.GoModuleFunction {
+	return api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+		p.host.calls[name]++
+		fn(guest{ctx: ctx, mod: mod}, stack)
+	})
+}
+
+// initialize runs package initializers so Wire has populated the exports.
+func (p *plugin) initialize() error {
+	for _, name := range []string{"_initialize", "wasi:cli/run@0.2.0#run", "_start"} {
+		fn := p.mod.ExportedFunction(name)
+		if fn == nil {
+			continue
+		}
+		_, err := fn.Call(p.ctx)
+		var exit *sys.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 0 && name == "_start" {
+			return errors.New("module exited after main; build it as a reactor (e.g. -buildmode=c-shared)")
+		}
+		return err
+	}
+	return nil
+}
+
+func (p *plugin) close() {
+	p.rt.Close(p.ctx)
+}
+
+// call invokes a mapper export and passes the guest pointer to its result
+// to lift, which must copy out everything it needs: the export's
+// cabi_post_ function, if the module has one, runs next and may free the
+// result. Go guests export none, leaving results to their garbage collector.
+func (p *plugin) call(name string, lift func(g guest, ret uint32), params ...uint64) (err error) {
+	full := mapperPrefix + p.version + "#" + name
+	fn := p.mod.ExportedFunction(full)
+	if fn == nil {
+		return fmt.Errorf("missing export %s", full)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	res, err := fn.Call(p.ctx, params...)
+	if err != nil {
+		return err
+	}
+	lift(guest{ctx: p.ctx, mod: p.mod}, api.DecodeU32(res[0]))
+	if post := p.mod.ExportedFunction("cabi_post_" + full); post != nil {
+		if _, err := post.Call(p.ctx, res...); err != nil {
+			return fmt.Errorf("cabi_post_%s: %w", full, err)
+		}
+	}
+	return nil
+}
+
+func (p *plugin) metadata() (name, version string, err error) {
+	err = p.call("metadata", func(g guest, ret uint32) {
+		name, version = g.stringAt(ret), g.stringAt(ret+8)
+	})
+	return name, version, err
+}
+
+func (p *plugin) probe() (out []string, err error) {
+	err = p.call("probe", func(g guest, ret uint32) {
+		base, n := g.u32(ret), g.u32(ret+4)
+		out = make([]string, n)
+		for i := uint32(0); i < n; i++ {
+			sel := base + i*selectorSize
+			out[i] = fmt.Sprintf("any%s all%s none%s",
+				g.preds(sel), g.preds(sel+8), g.preds(sel+16))
+		}
+	})
+	return out, err
+}
+
+// preds renders the list<pred> stored at addr.
+func (g guest) preds(addr uint32) string {
+	base, n := g.u32(addr), g.u32(addr+4)
+	parts := make([]string, n)
+	for i := uint32(0); i < n; i++ {
+		pred := base + i*predSize
+		path := g.stringAt(pred + 8)
+		switch g.u8(pred) {
+		case 0:
+			parts[i] = fmt.Sprintf("has(%s)", path)
+		case 1:
+			parts[i] = fmt.Sprintf("%s == %s", path, scalarString(g.scalarAt(pred+16)))
+		case 2:
+			parts[i] = fmt.Sprintf("prefix(%s, %q)", path, g.stringAt(pred+16))
+		case 3:
+			lst, cnt := g.u32(pred+16), g.u32(pred+20)
+			vals := make([]string, cnt)
+			for j := uint32(0); j < cnt; j++ {
+				vals[j] = scalarString(g.scalarAt(lst + j*scalarSize))
+			}
+			parts[i] = fmt.Sprintf("%s in [%s]", path, strings.Join(vals, ", "))
+		case 4:
+			parts[i] = fmt.Sprintf("%s > %v", path, math.Float64frombits(g.u64(pred+16)))
+		case 5:
+			parts[i] = fmt.Sprintf("%s =~ %q", path, g.stringAt(pred+16))
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// processLogs hands views to the plugin as logview resources and returns the
+// bytes it produced.
+func (p *plugin) processLogs(views []*jsonlog.View) (out []byte, err error) {
+	defer p.host.dropAll()
+	g := guest{ctx: p.ctx, mod: p.mod}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	list := g.alloc(uint32(4*len(views)), 4)
+	for i, v := range views {
+		g.putU32(list+uint32(4*i), p.host.open(v))
+	}
+	var failure error
+	err = p.call("process-logs", func(g guest, ret uint32) {
+		if g.u8(ret) != 0 {
+			failure = errors.New(g.stringAt(ret + 4))
+			return
+		}
+		out = g.read(g.u32(ret+4), g.u32(ret+8))
+	}, uint64(list), uint64(len(views)))
+	if err != nil {
+		return nil, err
+	}
+	if failure != nil {
+		return nil, failure
+	}
+	return out, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+)
+
+func TestPluginCalls(t *testing.T) {
+	p := testPlugin(t, component(section(0, wasmName("name")), moduleSection(testMapper())))
+	if p.version != "0.1.0" {
+		t.Errorf("version = %q", p.version)
+	}
+	posted := func() uint64 { return p.mod.ExportedGlobal("posted").Get() }
+
+	name, version, err := p.metadata()
+	if err != nil || name != "test" || version != "1.2.3" {
+		t.Errorf("metadata() = %q, %q, %v", name, version, err)
+	}
+	if n := posted(); n != 1 {
+		t.Errorf("post-return ran %d times after metadata, want 1", n)
+	}
+
+	v, err := jsonlog.Parse([]byte(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.processLogs([]*jsonlog.View{v})
+	if err != nil || string(out) != "ok\n" {
+		t.Errorf("processLogs(1 log) = %q, %v", out, err)
+	}
+	if _, err := p.processLogs([]*jsonlog.View{v, v}); err == nil || err.Error() != "boom" {
+		t.Errorf("processLogs(2 logs) err = %v, want boom", err)
+	}
+	if n := posted(); n != 3 {
+		t.Errorf("post-return ran %d times in all, want 3", n)
+	}
+	if len(p.host.views) != 0 {
+		t.Errorf("%d logview handles left open", len(p.host.views))
+	}
+
+	// The test module does not export probe.
+	if _, err := p.probe(); err == nil || !strings.Contains(err.Error(), "missing export") {
+		t.Errorf("probe() err = %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, bin := range map[string][]byte{
+		"no mapper export": cat(wasmMagic, coreVersion),
+		"not wasm":         []byte("{}"),
+	} {
+		path := filepath.Join(t.TempDir(), "plugin.wasm")
+		if err := os.WriteFile(path, bin, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if p, err := load(context.Background(), path, newHost(nil, true)); err == nil {
+			p.close()
+			t.Errorf("%s: load succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Stream handles handed out by get-stdin/get-stdout/get-stderr.
+const (
+	stdinHandle uint32 = iota + 1
+	stdoutHandle
+	stderrHandle
+)
+
+var startTime = time.Now()
+
+// wasiFuncs implements the subset of WASI 0.2 that TinyGo's runtime touches
+// from inside a mapper: stdio, clocks, randomness, environment and exit.
+// Guest stdout and stderr both go to errOut so the mapper's output stays clean.
+func wasiFuncs(errOut io.Writer) map[string]map[string]hostFunc {
+	zero := func(size uint32) hostFunc {
+		return func(g guest, stack []uint64) {
+			ret := api.DecodeU32(stack[len(stack)-1])
+			g.putBytes(ret, make([]byte, size))
+		}
+	}
+	streamOK := func(g guest, stack []uint64) {
+		g.putU8(api.DecodeU32(stack[len(stack)-1]), 0)
+	}
+	write := func(g guest, stack []uint64) {
+		if h := api.DecodeU32(stack[0]); h == stdoutHandle || h == stderrHandle {
+			errOut.Write(g.read(api.DecodeU32(stack[1]), api.DecodeU32(stack[2])))
+		}
+		g.putU8(api.DecodeU32(stack[3]), 0)
+	}
+	randomBytes := func(g guest, stack []uint64) {
+		buf := make([]byte, stack[0])
+		rand.Read(buf)
+		ptr := g.putList(api.DecodeU32(stack[1]), len(buf), 1, 1)
+		g.putBytes(ptr, buf)
+	}
+	randomU64 := func(g guest, stack []uint64) {
+		var buf [8]byte
+		rand.Read(buf[:])
+		stack[0] = binary.LittleEndian.Uint64(buf[:])
+	}
+	wallNow := func(g guest, stack []uint64) {
+		ret := api.DecodeU32(stack[0])
+		now := time.Now()
+		g.putU64(ret, uint64(now.Unix()))
+		g.putU32(ret+8, uint32(now.Nanosecond()))
+	}
+
+	return map[string]map[string]hostFunc{
+		"wasi:cli/environment": {
+			"get-environment": zero(8),
+			"get-arguments":   zero(8),
+			"initial-cwd":     zero(12),
+		},
+		"wasi:cli/exit": {
+			"exit": func(g guest, stack []uint64) {
+				code := api.DecodeU32(stack[0])
+				g.mod.CloseWithExitCode(g.ctx, code)
+				panic(sys.NewExitError(code))
+			},
+		},
+		"wasi:cli/stdin":           {"get-stdin": func(g guest, stack []uint64) { stack[0] = uint64(stdinHandle) }},
+		"wasi:cli/stdout":          {"get-stdout": func(g guest, stack []uint64) { stack[0] = uint64(stdoutHandle) }},
+		"wasi:cli/stderr":          {"get-stderr": func(g guest, stack []uint64) { stack[0] = uint64(stderrHandle) }},
+		"wasi:cli/terminal-stdin":  {"get-terminal-stdin": zero(8)},
+		"wasi:cli/terminal-stdout": {"get-terminal-stdout": zero(8)},
+		"wasi:cli/terminal-stderr": {"get-terminal-stderr": zero(8)},
+		"wasi:io/streams": {
+			"[method]output-stream.check-write": func(g guest, stack []uint64) {
+				ret := api.DecodeU32(stack[1])
+				g.putU8(ret, 0)
+				g.putU64(ret+8, 1<<20)
+			},
+			"[method]output-stream.write":                    write,
+			"[method]output-stream.blocking-write-and-flush": write,
+			"[method]output-stream.flush":                    streamOK,
+			"[method]output-stream.blocking-flush":           streamOK,
+		},
+		"wasi:clocks/wall-clock": {
+			"now":        wallNow,
+			"resolution": func(g guest, stack []uint64) { ret := api.DecodeU32(stack[0]); g.putU64(ret, 0); g.putU32(ret+8, 1) },
+		},
+		"wasi:clocks/monotonic-clock": {
+			"now":        func(g guest, stack []uint64) { stack[0] = uint64(time.Since(startTime)) },
+			"resolution": func(g guest, stack []uint64) { stack[0] = 1 },
+		},
+		"wasi:random/random": {
+			"get-random-bytes": randomBytes,
+			"get-random-u64":   randomU64,
+		},
+		"wasi:random/insecure": {
+			"get-insecure-random-bytes": randomBytes,
+			"get-insecure-random-u64":   randomU64,
+		},
+		"wasi:random/insecure-seed": {
+			"insecure-seed": func(g guest, stack []uint64) {
+				var buf [16]byte
+				rand.Read(buf[:])
+				g.putBytes(api.DecodeU32(stack[0]), buf[:])
+			},
+		},
+		"wasi:filesystem/preopens": {
+			"get-directories": zero(8),
+		},
+	}
+}
+
+// unversioned strips the "@x.y.z" suffix from an import module name.
+func unversioned(module string) string {
+	if i := strings.IndexByte(module, '@'); i >= 0 {
+		return module[:i]
+	}
+	return module
+}
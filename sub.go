@@ -0,0 +1,95 @@
+package tangent_sdk
+
+import (
+	"fmt"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/logsource"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+
+	"go.bytecodealliance.org/cm"
+)
+
+// Sub returns a view of the object or list element at path. Paths passed to
+// the view are relative to path, and "" addresses path itself:
+//
+//	res := l.Sub("detail.resource")
+//	id := res.GetString("instanceDetails.instanceId")
+//
+// The view reads through l and needs no cleanup. Its Log method returns the
+// whole original log.
+func (v Log) Sub(path string) Log {
+	if path == "" {
+		return v
+	}
+	if sub, ok := v.logview.(*subSource); ok {
+		return Log{logview: &subSource{src: sub.src, prefix: joinPath(sub.prefix, path)}}
+	}
+	return Log{logview: &subSource{src: v.logview, prefix: path}}
+}
+
+// Each calls fn with a Sub view of each element of the list at path, in
+// order, stopping at the first error and returning it:
+//
+//	err := l.Each("detail.findings", func(i int, f tangent_sdk.Log) error {
+//		out.Findings = append(out.Findings, Finding{ID: *f.GetString("id")})
+//		return nil
+//	})
+//
+// A missing path has no elements. Each returns an error if path holds an
+// object with members rather than a list.
+func (v Log) Each(path string, fn func(i int, elem Log) error) error {
+	n := v.Len(path)
+	if n == nil || *n == 0 {
+		return nil
+	}
+	if len(v.Keys(path)) > 0 {
+		return fmt.Errorf("tangent: Each: %s is an object, not a list", path)
+	}
+	for i := 0; i < int(*n); i++ {
+		if err := fn(i, v.Sub(indexPath(path, i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subSource resolves paths under prefix in src.
+type subSource struct {
+	src    logsource.Source
+	prefix string
+}
+
+func (s *subSource) path(rel string) string {
+	return joinPath(s.prefix, rel)
+}
+
+func (s *subSource) Get(path string) cm.Option[log.Scalar] {
+	return s.src.Get(s.path(path))
+}
+
+func (s *subSource) GetList(path string) cm.Option[cm.List[log.Scalar]] {
+	return s.src.GetList(s.path(path))
+}
+
+func (s *subSource) GetMap(path string) cm.Option[cm.List[cm.Tuple[string, log.Scalar]]] {
+	return s.src.GetMap(s.path(path))
+}
+
+func (s *subSource) Has(path string) bool {
+	return s.src.Has(s.path(path))
+}
+
+func (s *subSource) Keys(path string) cm.List[string] {
+	return s.src.Keys(s.path(path))
+}
+
+func (s *subSource) Len(path string) cm.Option[uint32] {
+	return s.src.Len(s.path(path))
+}
+
+func (s *subSource) Log() string {
+	return s.src.Log()
+}
+
+// ResourceDrop is a no-op; the parent Log owns the resource.
+func (s *subSource) ResourceDrop() {}
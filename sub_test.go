@@ -0,0 +1,135 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const subLog = `{
+	"detail": {
+		"resource": {"id": "r-1", "tags": ["a", "b"]},
+		"findings": [{"id": "f-1", "hits": [1, 2]}, {"id": "f-2", "hits": []}],
+		"matrix": [[1, 2], [3, 4]],
+		"empty": {}
+	},
+	"names": ["x", "y", "z"]
+}`
+
+func TestSub(t *testing.T) {
+	l := testLogs(t, subLog)[0]
+	tests := []struct {
+		view   Log
+		prefix string
+		path   string
+		want   string
+	}{
+		{l.Sub("detail.resource"), "detail.resource", "id", "r-1"},
+		{l.Sub("detail").Sub("resource"), "detail.resource", "tags[1]", "b"},
+		{l.Sub("detail.resource").Sub("tags[0]"), "detail.resource.tags[0]", "", "a"},
+		{l.Sub("detail.findings[1]"), "detail.findings[1]", "id", "f-2"},
+		{l.Sub("detail.findings").Sub("[0]"), "detail.findings[0]", "id", "f-1"},
+		{l.Sub("detail.matrix[1]").Sub("[0]"), "detail.matrix[1][0]", "", "3"},
+		{l.Sub("names"), "names", "[2]", "z"},
+		{l.Sub("detail").Sub("").Sub("resource"), "detail.resource", "id", "r-1"},
+	}
+	for _, tt := range tests {
+		sub, ok := tt.view.logview.(*subSource)
+		if !ok || sub.prefix != tt.prefix {
+			t.Errorf("view of %s: source %#v", tt.prefix, tt.view.logview)
+			continue
+		}
+		v, ok := tt.view.Get(tt.path)
+		if got := fmt.Sprint(v.Interface()); !ok || got != tt.want {
+			t.Errorf("Sub(%s).Get(%q) = %s, %t; want %s", tt.prefix, tt.path, got, ok, tt.want)
+		}
+		if tt.view.Log() != l.Log() {
+			t.Errorf("Sub(%s).Log() = %s, want the whole log", tt.prefix, tt.view.Log())
+		}
+	}
+
+	if sub := l.Sub(""); sub != l {
+		t.Error(`Sub("") is not the view itself`)
+	}
+	res := l.Sub("detail.resource")
+	if sub := res.Sub(""); sub != res {
+		t.Error(`Sub(...).Sub("") is not the view itself`)
+	}
+	if keys := res.Keys(""); !slices.Equal(keys, []string{"id", "tags"}) {
+		t.Errorf(`Sub(detail.resource).Keys("") = %q`, keys)
+	}
+	if n := res.Len("tags"); n == nil || *n != 2 {
+		t.Errorf("Sub(detail.resource).Len(tags) = %v", n)
+	}
+	if l.Sub("missing").Has("") || l.Sub("missing").Has("id") {
+		t.Error("a view of a missing path has members")
+	}
+}
+
+func TestEach(t *testing.T) {
+	l := testLogs(t, subLog)[0]
+
+	var ids []string
+	err := l.Each("detail.findings", func(i int, f Log) error {
+		ids = append(ids, fmt.Sprintf("%d:%s:%d", i, *f.GetString("id"), *f.Len("hits")))
+		return nil
+	})
+	if err != nil || !slices.Equal(ids, []string{"0:f-1:2", "1:f-2:0"}) {
+		t.Errorf("Each(detail.findings) = %q, %v", ids, err)
+	}
+
+	// Scalar elements are read at "".
+	var names []string
+	err = l.Each("names", func(i int, n Log) error {
+		names = append(names, *n.GetString(""))
+		return nil
+	})
+	if err != nil || strings.Join(names, "") != "xyz" {
+		t.Errorf("Each(names) = %q, %v", names, err)
+	}
+
+	// Paths inside a view are relative to it, including nested lists.
+	var sums []int64
+	err = l.Sub("detail").Each("matrix", func(i int, row Log) error {
+		var sum int64
+		err := row.Each("", func(j int, cell Log) error {
+			sum += *cell.GetInt64("")
+			return nil
+		})
+		sums = append(sums, sum)
+		return err
+	})
+	if err != nil || !slices.Equal(sums, []int64{3, 7}) {
+		t.Errorf("Each(matrix) row sums = %v, %v", sums, err)
+	}
+
+	calls := 0
+	count := func(int, Log) error { calls++; return nil }
+	for _, path := range []string{"missing", "detail.findings[1].hits", "detail.empty", "detail.resource.id"} {
+		if err := l.Each(path, count); err != nil {
+			t.Errorf("Each(%s) = %v", path, err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("Each called fn %d times for missing, empty and scalar paths", calls)
+	}
+
+	if err := l.Each("detail.resource", count); err == nil || !strings.Contains(err.Error(), "detail.resource is an object") {
+		t.Errorf("Each on an object = %v", err)
+	}
+
+	stop := errors.New("stop")
+	var seen []int
+	err = l.Each("names", func(i int, _ Log) error {
+		seen = append(seen, i)
+		if i == 1 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || !slices.Equal(seen, []int{0, 1}) {
+		t.Errorf("Each with a failing callback = %v after %v", err, seen)
+	}
+}
@@ -0,0 +1,70 @@
+// Package tangenttest provides an in-process stand-in for the Tangent host so
+// mapper handlers can be exercised with a plain `go test`.
+//
+// Logs built here answer Get, GetList, GetMap, Has, Keys, Len and Log for
+// dot/bracket paths such as "detail.findings[0].CompanyName", resolved in
+// process. A test passing here shows the handler is right about the
+// document as this package reads it; the host resolves paths with its own
+// implementation, which this one is not checked against, so edge cases such
+// as key order, number kinds and unusual keys may differ.
+package tangenttest
+
+import (
+	"bufio"
+	"bytes"
+
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+	"github.com/telophasehq/tangent-sdk-go/internal/sdkinternal"
+)
+
+func newLog(v *jsonlog.View) tangent_sdk.Log {
+	return sdkinternal.NewLog(v).(tangent_sdk.Log)
+}
+
+// NewLog builds a Log from a single JSON document.
+func NewLog(doc []byte) (tangent_sdk.Log, error) {
+	v, err := jsonlog.Parse(doc)
+	if err != nil {
+		return tangent_sdk.Log{}, err
+	}
+	return newLog(v), nil
+}
+
+// MustLog is like NewLog but panics if doc is not valid JSON. It is intended
+// for table-driven tests.
+func MustLog(doc string) tangent_sdk.Log {
+	l, err := NewLog([]byte(doc))
+	if err != nil {
+		panic("tangenttest: " + err.Error())
+	}
+	return l
+}
+
+// FromMap builds a Log from a decoded document.
+func FromMap(m map[string]any) (tangent_sdk.Log, error) {
+	v, err := jsonlog.FromValue(m)
+	if err != nil {
+		return tangent_sdk.Log{}, err
+	}
+	return newLog(v), nil
+}
+
+// NDJSON builds one Log per non-empty line of newline-delimited JSON.
+func NDJSON(data []byte) ([]tangent_sdk.Log, error) {
+	var logs []tangent_sdk.Log
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		l, err := NewLog(line)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, sc.Err()
+}
@@ -0,0 +1,78 @@
+package tangenttest_test
+
+import (
+	"errors"
+	"testing"
+
+	tangent_sdk "github.com/telophasehq/tangent-sdk-go"
+	"github.com/telophasehq/tangent-sdk-go/tangenttest"
+)
+
+// The handler lives in the same package as its Wire call, as in a typical
+// plugin's package main, and must still link under go test.
+
+type finding struct {
+	ID       string `json:"id"`
+	Severity int64  `json:"severity"`
+}
+
+func handle(l tangent_sdk.Log) (finding, error) {
+	id := l.GetString("detail.id")
+	if id == nil {
+		return finding{}, errors.New("missing detail.id")
+	}
+	var out finding
+	out.ID = *id
+	if sev := l.GetInt64("detail.severity"); sev != nil {
+		out.Severity = *sev
+	}
+	return out, nil
+}
+
+func init() {
+	tangent_sdk.Wire[finding](
+		tangent_sdk.Metadata{Name: "test", Version: "0.1.0"},
+		[]tangent_sdk.Selector{{All: []tangent_sdk.Predicate{tangent_sdk.Has("detail.id")}}},
+		handle,
+		nil,
+	)
+}
+
+func TestHandlerWithWire(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    finding
+		wantErr bool
+	}{
+		{name: "full", doc: `{"detail":{"id":"f-1","severity":7}}`, want: finding{ID: "f-1", Severity: 7}},
+		{name: "no severity", doc: `{"detail":{"id":"f-2"}}`, want: finding{ID: "f-2"}},
+		{name: "missing id", doc: `{"detail":{}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := handle(tangenttest.MustLog(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	logs, err := tangenttest.NDJSON([]byte("{\"a\":1}\n\n{\"a\":2}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d logs, want 2", len(logs))
+	}
+	for i, l := range logs {
+		if a := l.GetInt64("a"); a == nil || *a != int64(i+1) {
+			t.Errorf("log %d: a = %v", i, a)
+		}
+	}
+}
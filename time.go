@@ -0,0 +1,166 @@
+package tangent_sdk
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat identifies how ParseTime interpreted a timestamp.
+type TimeFormat uint8
+
+const (
+	TimeUnknown      TimeFormat = iota // missing, or no format matched
+	TimeLayout                         // one of the caller's layouts
+	TimeRFC3339                        // RFC 3339, with or without fractional seconds
+	TimeEpochSeconds                   // Unix seconds, possibly fractional
+	TimeEpochMillis                    // Unix milliseconds
+	TimeEpochMicros                    // Unix microseconds
+	TimeEpochNanos                     // Unix nanoseconds
+	TimeSyslog                         // "Jan _2 15:04:05", year inferred
+	TimeApache                         // "02/Jan/2006:15:04:05 -0700"
+)
+
+func (f TimeFormat) String() string {
+	switch f {
+	case TimeLayout:
+		return "layout"
+	case TimeRFC3339:
+		return "rfc3339"
+	case TimeEpochSeconds:
+		return "epoch_s"
+	case TimeEpochMillis:
+		return "epoch_ms"
+	case TimeEpochMicros:
+		return "epoch_us"
+	case TimeEpochNanos:
+		return "epoch_ns"
+	case TimeSyslog:
+		return "syslog"
+	case TimeApache:
+		return "apache"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	syslogLayout = time.Stamp // "Jan _2 15:04:05"
+	apacheLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// GetTime parses the timestamp at path with ParseTime.
+//
+//	ts, f := l.GetTime("eventTime")
+//	if f == tangent_sdk.TimeUnknown {
+//		return out, errors.New("eventTime is not a timestamp")
+//	}
+func (v Log) GetTime(path string, layouts ...string) (time.Time, TimeFormat) {
+	val, ok := v.Get(path)
+	if !ok {
+		return time.Time{}, TimeUnknown
+	}
+	return ParseTime(val, layouts...)
+}
+
+// ParseTime interprets a scalar as a timestamp and reports the format it
+// matched. String scalars are tried against layouts first, then RFC 3339,
+// Apache access log and syslog formats. Numbers, and strings holding only a
+// number, are Unix epoch times whose unit is chosen by magnitude: seconds
+// below 1e11 (until year 5138), then milliseconds, microseconds and
+// nanoseconds.
+//
+// Syslog timestamps have no year or zone. They are read as UTC in the
+// current year, or the previous one if that would put them more than a day
+// in the future, as for December logs read in January.
+func ParseTime(v Value, layouts ...string) (time.Time, TimeFormat) {
+	switch v.Kind() {
+	case KindInt:
+		i, _ := v.AsInt()
+		return epochInt(i)
+	case KindFloat:
+		f, _ := v.AsFloat()
+		return epochFloat(f)
+	case KindString:
+		s, _ := v.AsString()
+		return parseTimeString(strings.TrimSpace(s), layouts)
+	}
+	return time.Time{}, TimeUnknown
+}
+
+func parseTimeString(s string, layouts []string) (time.Time, TimeFormat) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, TimeLayout
+		}
+	}
+	if s == "" {
+		return time.Time{}, TimeUnknown
+	}
+	if c := s[0]; c == '-' || c == '+' || isDigit(c) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return epochInt(i)
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return epochFloat(f)
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, TimeRFC3339
+	}
+	if t, err := time.Parse(apacheLayout, s); err == nil {
+		return t, TimeApache
+	}
+	if t, err := time.Parse(syslogLayout, s); err == nil {
+		return withSyslogYear(t, wallNow()), TimeSyslog
+	}
+	return time.Time{}, TimeUnknown
+}
+
+// withSyslogYear moves t, parsed without a year, into now's year or the one
+// before.
+func withSyslogYear(t, now time.Time) time.Time {
+	now = now.UTC()
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+func epochInt(i int64) (time.Time, TimeFormat) {
+	abs := i
+	if i < 0 {
+		// -math.MinInt64 overflows back to itself; it is nanoseconds either
+		// way.
+		abs = -i
+		if abs < 0 {
+			abs = math.MaxInt64
+		}
+	}
+	switch {
+	case abs < 1e11:
+		return time.Unix(i, 0).UTC(), TimeEpochSeconds
+	case abs < 1e14:
+		return time.UnixMilli(i).UTC(), TimeEpochMillis
+	case abs < 1e17:
+		return time.UnixMicro(i).UTC(), TimeEpochMicros
+	default:
+		return time.Unix(0, i).UTC(), TimeEpochNanos
+	}
+}
+
+func epochFloat(f float64) (time.Time, TimeFormat) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, TimeUnknown
+	}
+	if math.Abs(f) >= 1e11 {
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return time.Time{}, TimeUnknown
+		}
+		return epochInt(int64(f))
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), TimeEpochSeconds
+}
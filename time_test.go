@@ -0,0 +1,97 @@
+package tangent_sdk
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		doc     string // JSON value
+		layouts []string
+		want    time.Time
+		format  TimeFormat
+	}{
+		{`"2024-01-02T03:04:05Z"`, nil, at, TimeRFC3339},
+		{`"2024-01-02T03:04:05.5Z"`, nil, at.Add(500 * time.Millisecond), TimeRFC3339},
+		{`"2024-01-02T05:04:05+02:00"`, nil, at, TimeRFC3339},
+		{`" 2024-01-02T03:04:05Z "`, nil, at, TimeRFC3339},
+		{`"02/Jan/2024:05:04:05 +0200"`, nil, at, TimeApache},
+		{`"2024-01-02 03:04:05"`, []string{time.DateTime}, at, TimeLayout},
+		{`"2024-01-02T03:04:05Z"`, []string{time.DateTime}, at, TimeRFC3339},
+		{`1704164645`, nil, at, TimeEpochSeconds},
+		{`1704164645.25`, nil, at.Add(250 * time.Millisecond), TimeEpochSeconds},
+		{`"1704164645"`, nil, at, TimeEpochSeconds},
+		{`1704164645000`, nil, at, TimeEpochMillis},
+		{`"1704164645000000"`, nil, at, TimeEpochMicros},
+		{`1704164645000000000`, nil, at, TimeEpochNanos},
+		{`1.704164645e12`, nil, at, TimeEpochMillis},
+		{`-86400`, nil, time.Unix(-86400, 0).UTC(), TimeEpochSeconds},
+		{`""`, nil, time.Time{}, TimeUnknown},
+		{`"yesterday"`, nil, time.Time{}, TimeUnknown},
+		{`"2024-13-02T03:04:05Z"`, nil, time.Time{}, TimeUnknown},
+		{`1e300`, nil, time.Time{}, TimeUnknown},
+		{`true`, nil, time.Time{}, TimeUnknown},
+	}
+	for _, tt := range tests {
+		v, ok := testLogs(t, `{"t":`+tt.doc+`}`)[0].Get("t")
+		if !ok {
+			t.Fatalf("%s: not a scalar", tt.doc)
+		}
+		got, f := ParseTime(v, tt.layouts...)
+		if f != tt.format || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%s) = %s, %s; want %s, %s", tt.doc, got, f, tt.want, tt.format)
+		}
+	}
+}
+
+func TestSyslogYear(t *testing.T) {
+	tests := []struct {
+		stamp string
+		now   time.Time
+		want  time.Time
+	}{
+		{"Jan  2 03:04:05", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"Dec 31 23:00:00", time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)},
+		{"Jan  2 03:04:05", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		parsed, err := time.Parse(syslogLayout, tt.stamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := withSyslogYear(parsed, tt.now); !got.Equal(tt.want) {
+			t.Errorf("withSyslogYear(%q, %s) = %s, want %s", tt.stamp, tt.now, got, tt.want)
+		}
+	}
+	v, _ := testLogs(t, `{"t":"Jan  2 03:04:05"}`)[0].Get("t")
+	if _, f := ParseTime(v); f != TimeSyslog {
+		t.Errorf("ParseTime(syslog) format = %s", f)
+	}
+}
+
+func TestEpochIntExtremes(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want TimeFormat
+	}{
+		{0, TimeEpochSeconds},
+		{-1e10, TimeEpochSeconds},
+		{-1e13, TimeEpochMillis},
+		{-1e16, TimeEpochMicros},
+		{math.MaxInt64, TimeEpochNanos},
+		{math.MinInt64, TimeEpochNanos},
+		{math.MinInt64 + 1, TimeEpochNanos},
+	}
+	for _, tt := range tests {
+		ts, f := epochInt(tt.in)
+		if f != tt.want {
+			t.Errorf("epochInt(%d) format = %s, want %s", tt.in, f, tt.want)
+		}
+		if f == TimeEpochNanos && ts.UnixNano() != tt.in {
+			t.Errorf("epochInt(%d) = %s", tt.in, ts)
+		}
+	}
+}
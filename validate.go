@@ -0,0 +1,279 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/logpath"
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+)
+
+// ErrNeverMatches reports a selector whose predicates contradict each other,
+// such as two different EqString values for the same path in All.
+var ErrNeverMatches = errors.New("selector can never match")
+
+// errEmptyPredicate reports a zero Predicate value, which has no host
+// predicates to send.
+var errEmptyPredicate = errors.New("empty predicate: build predicates with Has, EqString and the other constructors")
+
+// SelectorError describes one problem with a selector.
+type SelectorError struct {
+	Index    int // position in the list passed to Validate
+	Selector Selector
+	Err      error
+}
+
+func (e *SelectorError) Error() string {
+	return fmt.Sprintf("selector %d (%s): %v", e.Index, e.Selector, e.Err)
+}
+
+func (e *SelectorError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every problem Validate found.
+type ValidationError struct {
+	Errors []*SelectorError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, se := range e.Errors {
+		msgs[i] = se.Error()
+	}
+	return fmt.Sprintf("probe: %d selector error(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	out := make([]error, len(e.Errors))
+	for i, se := range e.Errors {
+		out[i] = se
+	}
+	return out
+}
+
+// Validate checks selectors for mistakes the host would otherwise only hit
+// at runtime: malformed paths, regexes that don't compile, and selectors
+// that can never match. It returns a *ValidationError, or nil.
+//
+// Wire and its variants run Validate when the host probes the plugin. A
+// malformed path or regex fails the probe; a selector that can never match
+// is only reported on stderr, since the host can still evaluate it. A test
+// calling Validate on the same selectors catches both before deploying.
+func Validate(selectors []Selector) error {
+	var errs []*SelectorError
+	for i, s := range selectors {
+		for _, err := range s.validate() {
+			errs = append(errs, &SelectorError{Index: i, Selector: s, Err: err})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// splitNeverMatches separates the ErrNeverMatches entries of an error from
+// Validate from the rest. Either result may be nil.
+func splitNeverMatches(err error) (definite, never error) {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return err, nil
+	}
+	var d, n []*SelectorError
+	for _, se := range ve.Errors {
+		if errors.Is(se.Err, ErrNeverMatches) {
+			n = append(n, se)
+		} else {
+			d = append(d, se)
+		}
+	}
+	if len(d) > 0 {
+		definite = &ValidationError{Errors: d}
+	}
+	if len(n) > 0 {
+		never = &ValidationError{Errors: n}
+	}
+	return definite, never
+}
+
+func (s Selector) validate() []error {
+	var errs []error
+	for _, group := range [][]Predicate{s.Any, s.All, s.None} {
+		for _, p := range group {
+			if len(p.terms) == 0 {
+				errs = append(errs, errEmptyPredicate)
+				continue
+			}
+			for _, t := range p.terms {
+				if err := t.validate(); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", p, err))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// s can match if any of its host selectors can.
+	reason := "it expands to no host selectors"
+	for i, c := range s.clauses() {
+		r := c.conflict()
+		if r == "" {
+			return nil
+		}
+		if i == 0 {
+			reason = r
+		}
+	}
+	return []error{fmt.Errorf("%w: %s", ErrNeverMatches, reason)}
+}
+
+func (t term) validate() error {
+	if _, err := logpath.Parse(t.path); err != nil {
+		return err
+	}
+	if t.op == opRegex {
+		if _, err := regexp.Compile(t.arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conflict explains why no log can satisfy c, or returns "" if one might.
+func (c clause) conflict() string {
+	base := make(conj, 0, len(c.all)+len(c.none))
+	base = append(base, c.all...)
+	for _, t := range c.none {
+		base = append(base, not(t))
+	}
+	if r := base.conflict(); r != "" || len(c.any) == 0 {
+		return r
+	}
+	var first string
+	for _, t := range c.any {
+		r := append(append(conj(nil), base...), t).conflict()
+		if r == "" {
+			return ""
+		}
+		if first == "" {
+			first = r
+		}
+	}
+	return "no Any predicate can match: " + first
+}
+
+// conflict explains why no log can satisfy every term of c, or returns "" if
+// one might. Terms only constrain their own path, so each path is checked on
+// its own.
+func (c conj) conflict() string {
+	byPath := map[string]conj{}
+	var paths []string
+	for _, t := range c {
+		if _, ok := byPath[t.path]; !ok {
+			paths = append(paths, t.path)
+		}
+		byPath[t.path] = append(byPath[t.path], t)
+	}
+	for _, path := range paths {
+		if r := byPath[path].pathConflict(); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+// pathConflict is conflict for terms that all share a path.
+func (c conj) pathConflict() string {
+	var (
+		missing *term // negated has
+		pinned  *term // first eq or in
+		str     *term // first prefix or regex
+		lo, hi  *term // tightest gt and negated gt
+	)
+	for i := range c {
+		t := &c[i]
+		for _, u := range c[:i] {
+			if u.equal(not(*t)) {
+				return fmt.Sprintf("%s contradicts %s", u, t)
+			}
+		}
+		if t.negate {
+			switch {
+			case t.op == opHas:
+				missing = t
+			case t.op == opGt && (hi == nil || t.num < hi.num):
+				hi = t
+			}
+			continue
+		}
+		switch t.op {
+		case opEq, opIn:
+			if len(t.values) == 0 {
+				return fmt.Sprintf("%s matches nothing", t)
+			}
+			if pinned == nil {
+				pinned = t
+			}
+		case opPrefix, opRegex:
+			if str == nil {
+				str = t
+			}
+		case opGt:
+			if lo == nil || t.num > lo.num {
+				lo = t
+			}
+		}
+	}
+
+	if missing != nil {
+		for _, t := range c {
+			if !t.negate {
+				return fmt.Sprintf("%s contradicts %s", t, missing)
+			}
+		}
+	}
+	if pinned != nil {
+		// The value must be one of pinned's; every other term must agree
+		// with at least one of them.
+		values := pinned.values
+		for i := range c {
+			t := &c[i]
+			if t == pinned || (t.negate && t.op == opHas) {
+				continue
+			}
+			var keep []log.Scalar
+			for _, v := range values {
+				if t.valueMatches(valueOf(v)) != t.negate {
+					keep = append(keep, v)
+				}
+			}
+			if len(keep) == 0 {
+				return fmt.Sprintf("%s contradicts %s", pinned, t)
+			}
+			values = keep
+		}
+		return ""
+	}
+	if str != nil && lo != nil {
+		return fmt.Sprintf("%s (string) contradicts %s (number)", str, lo)
+	}
+	if lo != nil && hi != nil && hi.num <= lo.num {
+		return fmt.Sprintf("%s contradicts %s", lo, hi)
+	}
+	for i, t := range c {
+		if t.negate || t.op != opPrefix {
+			continue
+		}
+		for _, u := range c[:i] {
+			if !u.negate && u.op == opPrefix && !strings.HasPrefix(t.arg, u.arg) && !strings.HasPrefix(u.arg, t.arg) {
+				return fmt.Sprintf("%s contradicts %s", u, t)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package tangent_sdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/mapper"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		sel   Selector
+		never bool   // want ErrNeverMatches
+		msg   string // want this error, without the selector prefix; "" for none
+	}{
+		{Selector{All: []Predicate{EqString("a", "x"), Has("b")}}, false, ""},
+		{Selector{All: []Predicate{Gte("a", 3), Lte("a", 3)}}, false, ""},
+		{Selector{All: []Predicate{InStrings("a", "x", "y")}, None: []Predicate{EqString("a", "x")}}, false, ""},
+		{Selector{All: []Predicate{Prefix("a", "x"), Prefix("a", "xy")}}, false, ""},
+		{Selector{All: []Predicate{Has("a"), Has("b")}, None: []Predicate{Prefix("b", "x")}}, false, ""},
+		{Selector{All: []Predicate{EqString("a", "x"), EqString("a", "y")}}, true,
+			`selector can never match: a == "x" contradicts a == "y"`},
+		{Selector{All: []Predicate{Has("a")}, None: []Predicate{Has("a")}}, true,
+			`selector can never match: has(a) contradicts !has(a)`},
+		{Selector{All: []Predicate{Gt("a", 5), Lt("a", 3)}}, true,
+			`selector can never match: a > 5 contradicts a < 3`},
+		{Selector{All: []Predicate{Prefix("a", "x"), Prefix("a", "y")}}, true,
+			`selector can never match: prefix(a, "x") contradicts prefix(a, "y")`},
+		{Selector{All: []Predicate{Prefix("a", "x"), Gt("a", 1)}}, true,
+			`selector can never match: prefix(a, "x") (string) contradicts a > 1 (number)`},
+		{Selector{All: []Predicate{InStrings("a")}}, true,
+			`selector can never match: a in [] matches nothing`},
+		{Selector{All: []Predicate{InStrings("a", "x")}, None: []Predicate{EqString("a", "x")}}, true,
+			`selector can never match: a in ["x"] contradicts a != "x"`},
+		{Selector{Any: []Predicate{EqInt("a", 1), EqInt("a", 2)}, All: []Predicate{EqInt("a", 3)}}, true,
+			`selector can never match: no Any predicate can match: a == 3 contradicts a == 1`},
+		{Selector{All: []Predicate{Regex("a", "(")}}, false,
+			"a =~ \"(\": error parsing regexp: missing closing ): `(`"},
+		{Selector{All: []Predicate{Has("a[")}}, false,
+			`has(a[): invalid path "a[" at offset 1: unterminated '['`},
+	}
+	for _, tt := range tests {
+		err := Validate([]Selector{tt.sel})
+		if tt.msg == "" {
+			if err != nil {
+				t.Errorf("Validate(%s) = %v", tt.sel, err)
+			}
+			continue
+		}
+		var ve *ValidationError
+		if !errors.As(err, &ve) || len(ve.Errors) != 1 {
+			t.Errorf("Validate(%s) = %v, want one error", tt.sel, err)
+			continue
+		}
+		se := ve.Errors[0]
+		if got := se.Err.Error(); got != tt.msg {
+			t.Errorf("Validate(%s) = %q, want %q", tt.sel, got, tt.msg)
+		}
+		if errors.Is(err, ErrNeverMatches) != tt.never {
+			t.Errorf("Validate(%s): errors.Is(ErrNeverMatches) = %t", tt.sel, !tt.never)
+		}
+	}
+}
+
+func TestValidateIndexes(t *testing.T) {
+	err := Validate([]Selector{
+		{All: []Predicate{Has("a")}},
+		{All: []Predicate{Regex("a", "(")}},
+		{All: []Predicate{Has("a")}, None: []Predicate{Has("a")}},
+	})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Errors) != 2 || ve.Errors[0].Index != 1 || ve.Errors[1].Index != 2 {
+		t.Errorf("Validate = %v, want errors for selectors 1 and 2", err)
+	}
+}
+
+func TestSplitNeverMatches(t *testing.T) {
+	definite, never := splitNeverMatches(Validate([]Selector{
+		{All: []Predicate{Has("a")}, None: []Predicate{Has("a")}},
+		{All: []Predicate{Regex("a", "(")}},
+		{All: []Predicate{EqInt("a", 1), EqInt("a", 2)}},
+	}))
+	var d, n *ValidationError
+	if !errors.As(definite, &d) || len(d.Errors) != 1 || d.Errors[0].Index != 1 {
+		t.Errorf("definite = %v, want the regex error of selector 1", definite)
+	}
+	if !errors.As(never, &n) || len(n.Errors) != 2 || n.Errors[0].Index != 0 || n.Errors[1].Index != 2 {
+		t.Errorf("never = %v, want selectors 0 and 2", never)
+	}
+	if errors.Is(definite, ErrNeverMatches) {
+		t.Errorf("definite = %v includes ErrNeverMatches", definite)
+	}
+
+	if d, n := splitNeverMatches(nil); d != nil || n != nil {
+		t.Errorf("splitNeverMatches(nil) = %v, %v", d, n)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	probe := func(sels ...Selector) (n int, trapped any) {
+		defer func() { trapped = recover() }()
+		WireMulti(Metadata{Name: "test"}, sels, func(Log) ([]testOut, error) { return nil, nil })
+		return int(mapper.Exports.Probe().Len()), nil
+	}
+	if n, trapped := probe(Selector{All: []Predicate{Has("a")}, None: []Predicate{Has("a")}}); trapped != nil || n != 1 {
+		t.Errorf("probe of a never-matching selector = %d, %v; want it sent", n, trapped)
+	}
+	for _, sel := range []Selector{
+		{All: []Predicate{Regex("a", "(")}},
+		{All: []Predicate{Has("a[")}},
+		{All: []Predicate{{}}},
+	} {
+		if _, trapped := probe(sel); trapped == nil {
+			t.Errorf("probe(%s) did not trap", sel)
+		}
+	}
+}
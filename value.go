@@ -0,0 +1,139 @@
+package tangent_sdk
+
+import "github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+
+// Kind identifies the scalar type held by a Value.
+type Kind uint8
+
+const (
+	KindInvalid Kind = iota
+	KindString
+	KindInt
+	KindFloat
+	KindBool
+	KindBytes
+)
+
+var kindNames = [...]string{"invalid", "string", "int", "float", "bool", "bytes"}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Value is a typed scalar read from a log. The zero Value is invalid and
+// represents a missing or non-scalar field.
+type Value struct {
+	scalar log.Scalar
+	valid  bool
+}
+
+func valueOf(s log.Scalar) Value {
+	return Value{scalar: s, valid: true}
+}
+
+// Kind reports which scalar type v holds.
+func (v Value) Kind() Kind {
+	if !v.valid {
+		return KindInvalid
+	}
+	return Kind(v.scalar.Tag() + 1)
+}
+
+// IsValid reports whether v holds a scalar.
+func (v Value) IsValid() bool {
+	return v.valid
+}
+
+// AsString returns the string held by v.
+func (v Value) AsString() (string, bool) {
+	if s := v.scalar.Str(); v.valid && s != nil {
+		return *s, true
+	}
+	return "", false
+}
+
+// AsInt returns the integer held by v.
+func (v Value) AsInt() (int64, bool) {
+	if s := v.scalar.Int(); v.valid && s != nil {
+		return *s, true
+	}
+	return 0, false
+}
+
+// AsFloat returns the float held by v.
+func (v Value) AsFloat() (float64, bool) {
+	if s := v.scalar.Float(); v.valid && s != nil {
+		return *s, true
+	}
+	return 0, false
+}
+
+// AsBool returns the bool held by v.
+func (v Value) AsBool() (bool, bool) {
+	if s := v.scalar.Boolean(); v.valid && s != nil {
+		return *s, true
+	}
+	return false, false
+}
+
+// AsBytes returns a copy of the bytes held by v.
+func (v Value) AsBytes() ([]byte, bool) {
+	if s := v.scalar.Bytes(); v.valid && s != nil {
+		return append([]byte(nil), s.Slice()...), true
+	}
+	return nil, false
+}
+
+// Interface returns v as a string, int64, float64, bool or []byte, or nil if
+// v is invalid.
+func (v Value) Interface() any {
+	switch v.Kind() {
+	case KindString:
+		s, _ := v.AsString()
+		return s
+	case KindInt:
+		i, _ := v.AsInt()
+		return i
+	case KindFloat:
+		f, _ := v.AsFloat()
+		return f
+	case KindBool:
+		b, _ := v.AsBool()
+		return b
+	case KindBytes:
+		b, _ := v.AsBytes()
+		return b
+	}
+	return nil
+}
+
+// Field is one scalar member of an object read with GetMap.
+type Field struct {
+	Key   string
+	Value Value
+}
+
+// Fields is an ordered list of object members.
+type Fields []Field
+
+// Get returns the value stored under key.
+func (f Fields) Get(key string) (Value, bool) {
+	for i := range f {
+		if f[i].Key == key {
+			return f[i].Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Map returns the fields keyed by name.
+func (f Fields) Map() map[string]Value {
+	out := make(map[string]Value, len(f))
+	for _, fld := range f {
+		out[fld.Key] = fld.Value
+	}
+	return out
+}
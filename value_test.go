@@ -0,0 +1,151 @@
+package tangent_sdk
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
+	"go.bytecodealliance.org/cm"
+)
+
+func TestValueKinds(t *testing.T) {
+	tests := []struct {
+		v    Value
+		kind Kind
+		name string
+		want any
+	}{
+		{valueOf(log.ScalarStr("s")), KindString, "string", "s"},
+		{valueOf(log.ScalarStr("")), KindString, "string", ""},
+		{valueOf(log.ScalarInt(-3)), KindInt, "int", int64(-3)},
+		{valueOf(log.ScalarFloat(0.5)), KindFloat, "float", 0.5},
+		{valueOf(log.ScalarBoolean(false)), KindBool, "bool", false},
+		{valueOf(log.ScalarBytes(cm.ToList([]byte{1, 2}))), KindBytes, "bytes", []byte{1, 2}},
+		{Value{}, KindInvalid, "invalid", nil},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Kind(); got != tt.kind || got.String() != tt.name {
+			t.Errorf("%v: Kind() = %v (%d), want %s", tt.want, got, got, tt.name)
+		}
+		if tt.v.IsValid() != (tt.kind != KindInvalid) {
+			t.Errorf("%v: IsValid() = %t", tt.want, tt.v.IsValid())
+		}
+		if got := tt.v.Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Interface() = %#v, want %#v", got, tt.want)
+		}
+
+		// Each accessor succeeds only for its own kind.
+		s, sok := tt.v.AsString()
+		i, iok := tt.v.AsInt()
+		f, fok := tt.v.AsFloat()
+		b, bok := tt.v.AsBool()
+		by, byok := tt.v.AsBytes()
+		got := map[Kind]bool{KindString: sok, KindInt: iok, KindFloat: fok, KindBool: bok, KindBytes: byok}
+		for k, ok := range got {
+			if ok != (k == tt.kind) {
+				t.Errorf("%s value: As%s ok = %t", tt.name, k, ok)
+			}
+		}
+		if !sok && s != "" || !iok && i != 0 || !fok && f != 0 || !bok && b || !byok && by != nil {
+			t.Errorf("%s value: failed accessors returned non-zero values", tt.name)
+		}
+	}
+
+	if got := Kind(42).String(); got != "unknown" {
+		t.Errorf("Kind(42).String() = %q", got)
+	}
+
+	src := []byte{1, 2, 3}
+	v := valueOf(log.ScalarBytes(cm.ToList(src)))
+	b, _ := v.AsBytes()
+	b[0] = 9
+	if again, _ := v.AsBytes(); !bytes.Equal(again, []byte{1, 2, 3}) || src[0] != 1 {
+		t.Errorf("AsBytes returned shared memory: %v, %v", again, src)
+	}
+}
+
+func TestLogValues(t *testing.T) {
+	l := testLogs(t, `{
+		"s": "x", "i": 1, "f": 2.5, "b": true, "n": null,
+		"list": ["a", 1, 2.5, false, null, {"k": 1}, [2]], "empty": [],
+		"obj": {"b": 2, "a": "x", "nested": {"k": 1}, "l": [1], "n": null}, "none": {}
+	}`)[0]
+
+	for path, want := range map[string]any{"s": "x", "i": int64(1), "f": 2.5, "b": true, "list[1]": int64(1), "obj.a": "x"} {
+		if v, ok := l.Get(path); !ok || !reflect.DeepEqual(v.Interface(), want) {
+			t.Errorf("Get(%s) = %#v, %t; want %#v", path, v.Interface(), ok, want)
+		}
+	}
+	for _, path := range []string{"missing", "n", "list", "obj", "none", "list[9]"} {
+		if v, ok := l.Get(path); ok || v.IsValid() {
+			t.Errorf("Get(%s) = %v, %t; want an invalid Value", path, v.Interface(), ok)
+		}
+	}
+
+	// GetList keeps the scalar elements, in order.
+	vals, ok := l.GetList("list")
+	var got []any
+	for _, v := range vals {
+		got = append(got, v.Interface())
+	}
+	if want := []any{"a", int64(1), 2.5, false}; !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetList(list) = %#v, %t; want %#v", got, ok, want)
+	}
+	if vals, ok := l.GetList("empty"); !ok || vals == nil || len(vals) != 0 {
+		t.Errorf("GetList(empty) = %#v, %t; want an empty list", vals, ok)
+	}
+	for _, path := range []string{"missing", "obj", "s"} {
+		if vals, ok := l.GetList(path); ok || vals != nil {
+			t.Errorf("GetList(%s) = %#v, %t; want nil, false", path, vals, ok)
+		}
+	}
+
+	// The typed list getters keep only elements of their kind.
+	if ss, ok := l.GetStringList("list"); !ok || !slices.Equal(ss, []string{"a"}) {
+		t.Errorf("GetStringList(list) = %q, %t", ss, ok)
+	}
+	if is, ok := l.GetInt64List("list"); !ok || !slices.Equal(is, []int64{1}) {
+		t.Errorf("GetInt64List(list) = %v, %t", is, ok)
+	}
+	if fs, ok := l.GetFloat64List("list"); !ok || !slices.Equal(fs, []float64{2.5}) {
+		t.Errorf("GetFloat64List(list) = %v, %t", fs, ok)
+	}
+	if ss, ok := l.GetStringList("missing"); ok || ss != nil {
+		t.Errorf("GetStringList(missing) = %q, %t", ss, ok)
+	}
+
+	// GetMap keeps the scalar members.
+	fields, ok := l.GetMap("obj")
+	if !ok || len(fields) != 2 {
+		t.Fatalf("GetMap(obj) = %v, %t", fields, ok)
+	}
+	keys := []string{fields[0].Key, fields[1].Key}
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("GetMap(obj) keys = %q", keys)
+	}
+	if v, ok := fields.Get("b"); !ok || v.Interface() != int64(2) {
+		t.Errorf("Fields.Get(b) = %v, %t", v.Interface(), ok)
+	}
+	for _, key := range []string{"nested", "l", "n", "missing"} {
+		if v, ok := fields.Get(key); ok || v.IsValid() {
+			t.Errorf("Fields.Get(%s) = %v, %t", key, v.Interface(), ok)
+		}
+	}
+	m := fields.Map()
+	if len(m) != 2 || m["a"].Interface() != "x" || m["b"].Interface() != int64(2) {
+		t.Errorf("Fields.Map() = %v", m)
+	}
+	if fields, ok := l.GetMap("none"); !ok || fields == nil || len(fields) != 0 || len(fields.Map()) != 0 {
+		t.Errorf("GetMap(none) = %#v, %t; want empty fields", fields, ok)
+	}
+	for _, path := range []string{"missing", "list", "s"} {
+		if fields, ok := l.GetMap(path); ok || fields != nil {
+			t.Errorf("GetMap(%s) = %#v, %t; want nil, false", path, fields, ok)
+		}
+	}
+	if _, ok := Fields(nil).Get("a"); ok {
+		t.Error("Get on nil Fields found a member")
+	}
+}
@@ -3,10 +3,10 @@ package tangent_sdk
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"os"
 	"sync"
 
-	"github.com/mailru/easyjson"
-	"github.com/mailru/easyjson/jwriter"
 	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/log"
 	"github.com/telophasehq/tangent-sdk-go/internal/tangent/logs/mapper"
 
@@ -17,19 +17,84 @@ var (
 	bufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
 )
 
+// ErrSkip may be returned by a handler to drop a log without emitting output.
+// A batch handler returning ErrSkip drops the whole batch; use WireBatchMulti
+// to drop individual logs from a batch.
+var ErrSkip = errors.New("tangent: skip log")
+
 type ProcessLog[T any] func(Log) (T, error)
 type ProcessLogs[T any] func([]Log) ([]T, error)
 
+// ProcessLogMulti maps one log to zero or more outputs.
+type ProcessLogMulti[T any] func(Log) ([]T, error)
+
+// ProcessLogsMulti maps a batch of logs to zero or more outputs per log. The
+// result must hold one slice per log, in order.
+type ProcessLogsMulti[T any] func([]Log) ([][]T, error)
+
 // Wire connects metadata, probe selectors, and a handler to Tangent's ABI.
-func Wire[T any](meta Metadata, selectors []Selector, handler ProcessLog[T], batchHandler ProcessLogs[T]) {
+func Wire[T any](meta Metadata, selectors []Selector, handler ProcessLog[T], batchHandler ProcessLogs[T], opts ...Option) {
+	var multi ProcessLogMulti[T]
+	if handler != nil {
+		var one [1]T
+		multi = func(l Log) ([]T, error) {
+			out, err := handler(l)
+			if err != nil {
+				return nil, err
+			}
+			one[0] = out
+			return one[:], nil
+		}
+	}
+	var batchMulti ProcessLogsMulti[T]
+	if batchHandler != nil {
+		batchMulti = func(logs []Log) ([][]T, error) {
+			outs, err := batchHandler(logs)
+			if err != nil {
+				return nil, err
+			}
+			per := make([][]T, len(outs))
+			for i := range outs {
+				per[i] = outs[i : i+1 : i+1]
+			}
+			return per, nil
+		}
+	}
+	wire(meta, selectors, &processor[T]{name: meta.Name, handler: multi, batchHandler: batchMulti, opts: newOptions(opts)})
+}
+
+// WireMulti is like Wire, but the handler may emit any number of outputs per
+// log: none to filter it out, or several to fan it out.
+func WireMulti[T any](meta Metadata, selectors []Selector, handler ProcessLogMulti[T], opts ...Option) {
+	wire(meta, selectors, &processor[T]{name: meta.Name, handler: handler, opts: newOptions(opts)})
+}
+
+// WireBatchMulti is the batch form of WireMulti: the handler receives the
+// whole batch and returns the outputs of each log, so it can drop single logs
+// (an empty slice) or fan them out. Returning ErrSkip drops the whole batch.
+func WireBatchMulti[T any](meta Metadata, selectors []Selector, batchHandler ProcessLogsMulti[T], opts ...Option) {
+	wire(meta, selectors, &processor[T]{name: meta.Name, batchHandler: batchHandler, opts: newOptions(opts)})
+}
+
+func wire[T any](meta Metadata, selectors []Selector, p *processor[T]) {
 	mapper.Exports.Metadata = func() mapper.Meta {
 		return meta.ToMapper()
 	}
 
 	mapper.Exports.Probe = func() cm.List[mapper.Selector] {
-		mapped := make([]mapper.Selector, len(selectors))
+		definite, never := splitNeverMatches(Validate(selectors))
+		if definite != nil {
+			// probe can't return an error; trapping fails the plugin load
+			// with this message instead of running with a broken selector.
+			panic(definite.Error())
+		}
+		if never != nil {
+			// A dead selector is harmless to the host; flag it and load.
+			fmt.Fprintln(os.Stderr, "warning:", never)
+		}
+		mapped := make([]mapper.Selector, 0, len(selectors))
 		for i := range selectors {
-			mapped[i] = selectors[i].toMapper()
+			mapped = append(mapped, selectors[i].toMapper()...)
 		}
 		return cm.ToList(mapped)
 	}
@@ -39,65 +104,137 @@ func Wire[T any](meta Metadata, selectors []Selector, handler ProcessLog[T], bat
 		buf.Reset()
 		defer bufPool.Put(buf)
 
-		var jw jwriter.Writer
+		logs, drop := hostLogs(input)
+		err := p.process(buf, logs)
+		drop()
+		if err != nil {
+			res.SetErr(err.Error())
+			return
+		}
+		res.SetOK(cm.ToList(buf.Bytes()))
+		return
+	}
+}
+
+// processor runs a mapper's handlers over batches of logs. Handler panics
+// are recovered, where the runtime supports it, and reported as *PanicError.
+type processor[T any] struct {
+	name         string
+	handler      ProcessLogMulti[T]
+	batchHandler ProcessLogsMulti[T]
+	opts         *options
 
-		writeOut := func(out T) error {
-			outMarshal, ok := any(out).(easyjson.Marshaler)
-			if !ok {
-				return errors.New("output does not implement easyjson.Marshaler. Did you recompile?")
-			}
-			outMarshal.MarshalEasyJSON(&jw)
-			jw.RawByte('\n')
-			return nil
+	buf *bytes.Buffer
+}
+
+// process runs the handlers over logs and encodes their outputs into buf.
+func (p *processor[T]) process(buf *bytes.Buffer, logs []Log) error {
+	p.buf = buf
+	defer func() { p.buf = nil }()
+
+	if p.batchHandler != nil {
+		return p.processBatch(logs)
+	}
+	for i, l := range logs {
+		if err := p.processOne(i, l); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		items := append([]log.Logview(nil), input.Slice()...)
-		if batchHandler != nil {
-			var logviews []Log
-			for _, lv := range items {
-				logviews = append(logviews, Log{logview: lv})
-			}
-			outs, err := batchHandler(logviews)
-			for _, lv := range logviews {
-				lv.logview.ResourceDrop()
+func (p *processor[T]) processOne(i int, l Log) error {
+	outs, err := p.callHandler(i, l)
+	if errors.Is(err, ErrSkip) {
+		return nil
+	}
+	if err != nil {
+		return p.fail(i, l, err)
+	}
+	return p.writeOuts(outs)
+}
+
+func (p *processor[T]) processBatch(logs []Log) error {
+	index := -1
+	if len(logs) == 1 {
+		index = 0
+	}
+	per, err := p.callBatch(index, logs)
+	if errors.Is(err, ErrSkip) {
+		return nil
+	}
+	if err != nil && p.opts.isolate() && len(logs) > 1 {
+		// Retry each log on its own so only the failing ones are set aside.
+		for i, l := range logs {
+			per, err := p.callBatch(i, []Log{l})
+			if errors.Is(err, ErrSkip) {
+				continue
 			}
-			logviews = logviews[:0]
 			if err != nil {
-				res.SetErr(err.Error())
-				return
-			}
-			if len(outs) != len(items) {
-				res.SetErr("batchHandler returned wrong number of outputs")
-				return
-			}
-			for _, out := range outs {
-				if err := writeOut(out); err != nil {
-					res.SetErr(err.Error())
-					return
+				if err := p.fail(i, l, err); err != nil {
+					return err
 				}
+				continue
 			}
-		} else {
-			for _, lv := range items {
-				out, err := handler(Log{logview: lv})
-				lv.ResourceDrop()
-				if err != nil {
-					res.SetErr(err.Error())
-					return
-				}
-				if err := writeOut(out); err != nil {
-					res.SetErr(err.Error())
-					return
-				}
+			if err := p.writeBatch(per, 1); err != nil {
+				return err
 			}
 		}
+		return nil
+	}
+	if err != nil {
+		if len(logs) == 1 {
+			return p.fail(0, logs[0], err)
+		}
+		return err
+	}
+	return p.writeBatch(per, len(logs))
+}
 
-		if jw.Error != nil {
-			res.SetErr(jw.Error.Error())
-			return
+// writeBatch writes a batch handler's outputs for n logs.
+func (p *processor[T]) writeBatch(per [][]T, n int) error {
+	if len(per) != n {
+		return errors.New("batchHandler returned wrong number of outputs")
+	}
+	for _, outs := range per {
+		if err := p.writeOuts(outs); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		jw.DumpTo(buf)
-		res.SetOK(cm.ToList(buf.Bytes()))
-		return
+func (p *processor[T]) callHandler(i int, l Log) (outs []T, err error) {
+	defer recoverPanic(p.name, i, &err)
+	return p.handler(l)
+}
+
+func (p *processor[T]) callBatch(index int, logs []Log) (per [][]T, err error) {
+	defer recoverPanic(p.name, index, &err)
+	return p.batchHandler(logs)
+}
+
+// fail dead-letters the log at index i if isolation is enabled, and
+// otherwise returns err to fail the batch.
+func (p *processor[T]) fail(i int, l Log, err error) error {
+	if !p.opts.isolate() {
+		return err
+	}
+	dl := DeadLetter{Index: i, Error: err.Error(), Log: l.Log()}
+	if p.opts.deadLetter != nil {
+		p.opts.deadLetter(dl)
+	}
+	if p.opts.inlineDeadLetters {
+		return p.opts.encoder.Encode(p.buf, inlineDeadLetter{DeadLetter: dl})
+	}
+	return nil
+}
+
+func (p *processor[T]) writeOuts(outs []T) error {
+	for _, out := range outs {
+		if err := p.opts.encoder.Encode(p.buf, out); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -0,0 +1,154 @@
+package tangent_sdk
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/telophasehq/tangent-sdk-go/internal/jsonlog"
+)
+
+func testLogs(t testing.TB, docs ...string) []Log {
+	t.Helper()
+	logs := make([]Log, len(docs))
+	for i, doc := range docs {
+		v, err := jsonlog.Parse([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		logs[i] = newLog(v)
+	}
+	return logs
+}
+
+type testOut struct {
+	N int64 `json:"n"`
+}
+
+func runProcessor(t *testing.T, p *processor[testOut], logs []Log) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	err := p.process(&buf, logs)
+	return buf.String(), err
+}
+
+func TestProcessSkipAndFanOut(t *testing.T) {
+	p := &processor[testOut]{
+		handler: func(l Log) ([]testOut, error) {
+			n := *l.GetInt64("n")
+			if n == 0 {
+				return nil, ErrSkip
+			}
+			out := make([]testOut, n)
+			for i := range out {
+				out[i].N = n
+			}
+			return out, nil
+		},
+		opts: newOptions([]Option{WithEncoder(JSON)}),
+	}
+	got, err := runProcessor(t, p, testLogs(t, `{"n":1}`, `{"n":0}`, `{"n":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\n{\"n\":2}\n{\"n\":2}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestProcessBatchMulti(t *testing.T) {
+	p := &processor[testOut]{
+		batchHandler: func(logs []Log) ([][]testOut, error) {
+			per := make([][]testOut, len(logs))
+			for i, l := range logs {
+				for n := *l.GetInt64("n"); n > 0; n-- {
+					per[i] = append(per[i], testOut{N: int64(i)})
+				}
+			}
+			return per, nil
+		},
+		opts: newOptions([]Option{WithEncoder(JSON)}),
+	}
+	got, err := runProcessor(t, p, testLogs(t, `{"n":0}`, `{"n":2}`, `{"n":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\n{\"n\":1}\n{\"n\":2}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	p.batchHandler = func(logs []Log) ([][]testOut, error) { return nil, nil }
+	if _, err := runProcessor(t, p, testLogs(t, `{"n":1}`, `{"n":1}`)); err == nil || !strings.Contains(err.Error(), "wrong number") {
+		t.Errorf("short result: err = %v", err)
+	}
+
+	p.batchHandler = func(logs []Log) ([][]testOut, error) { return nil, ErrSkip }
+	if got, err := runProcessor(t, p, testLogs(t, `{"n":1}`, `{"n":1}`)); got != "" || err != nil {
+		t.Errorf("ErrSkip: got %q, %v", got, err)
+	}
+}
+
+func TestProcessBatchIsolation(t *testing.T) {
+	var calls int
+	var dead []DeadLetter
+	p := &processor[testOut]{
+		batchHandler: func(logs []Log) ([][]testOut, error) {
+			calls++
+			per := make([][]testOut, len(logs))
+			for i, l := range logs {
+				n := *l.GetInt64("n")
+				if n < 0 {
+					return nil, errors.New("negative")
+				}
+				per[i] = []testOut{{N: n}}
+			}
+			return per, nil
+		},
+		opts: newOptions([]Option{WithEncoder(JSON), WithDeadLetter(func(d DeadLetter) { dead = append(dead, d) })}),
+	}
+	got, err := runProcessor(t, p, testLogs(t, `{"n":1}`, `{"n":-1}`, `{"n":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\n{\"n\":3}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(dead) != 1 || dead[0].Index != 1 || dead[0].Error != "negative" {
+		t.Errorf("dead letters = %+v", dead)
+	}
+	// The failed batch is re-run once per log.
+	if calls != 4 {
+		t.Errorf("batch handler called %d times, want 4", calls)
+	}
+}
+
+func TestInlineDeadLettersNeedEncoderSupport(t *testing.T) {
+	for _, enc := range []Encoder{NDJSON, JSON, MessagePack} {
+		newOptions([]Option{WithEncoder(enc), WithInlineDeadLetters()})
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("ProtobufDelimited with WithInlineDeadLetters did not panic")
+		}
+	}()
+	newOptions([]Option{WithEncoder(ProtobufDelimited), WithInlineDeadLetters()})
+}
+
+func TestProcessRecoversPanics(t *testing.T) {
+	p := &processor[testOut]{
+		name: "test",
+		handler: func(l Log) ([]testOut, error) {
+			return []testOut{{N: *l.GetInt64("missing")}}, nil
+		},
+		opts: newOptions([]Option{WithEncoder(JSON)}),
+	}
+	_, err := runProcessor(t, p, testLogs(t, `{}`, `{}`))
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v, want *PanicError", err)
+	}
+	if pe.Plugin != "test" || pe.Index != 0 || len(pe.Stack) == 0 {
+		t.Errorf("got %+v", pe)
+	}
+}